@@ -18,6 +18,7 @@ import (
 
 type App interface {
 	Start() error
+	Redecode(chainName, contractName string) error
 }
 
 type app struct {
@@ -57,12 +58,13 @@ func (a *app) Start() error {
 
 	var outputServices []types.Service
 	var outputs types.Outputs
+	var checkpoints types.Checkpoints
 	if config.Outputs.Console == nil || !config.Outputs.Console.Disabled {
 		outputs = append(outputs, out.NewLoggerOutput(a.logger))
 	}
 
 	if config.Outputs.Postgres != nil {
-		pg := out.NewPostgres(a.logger, config.Outputs.Postgres.Retention)
+		pg := out.NewPostgres(a.logger, *config.Outputs.Postgres.Retention)
 		if err := pg.Connect(rootCtx, config.Outputs.Postgres.URL); err != nil {
 			return fmt.Errorf("failed to connect Postgres: url=%s", config.Outputs.Postgres.URL)
 		}
@@ -71,14 +73,35 @@ func (a *app) Start() error {
 		if err := pg.MigrateSchema(rootCtx, contracts); err != nil {
 			return fmt.Errorf("failed to migrate postgres schema: %v", err)
 		}
+		for chainName, chainConfig := range config.Chains {
+			if chainConfig.Blocks {
+				if err := pg.MigrateBlocksSchema(rootCtx, chainName); err != nil {
+					return fmt.Errorf("failed to migrate postgres blocks schema: %v", err)
+				}
+			}
+		}
 
 		outputServices = append(outputServices, pg)
 		outputs = append(outputs, pg)
+		checkpoints = pg
+	} else {
+		checkpointsFile := config.Checkpoints.File
+		if !path.IsAbs(checkpointsFile) {
+			checkpointsFile = path.Join(path.Dir(a.configPath), checkpointsFile)
+		}
+		checkpoints = NewFileCheckpoints(checkpointsFile)
 	}
 
 	var chainServices []types.Service
 	for chainName, chainContracts := range contracts {
-		chain := NewChain(chainName, config.Chains[chainName], chainContracts, a.logger, outputs)
+		checkpoint, err := checkpoints.LoadCheckpoint(rootCtx, chainName)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint for chain %s: %v", chainName, err)
+		}
+		if err := RestoreDiscoveredAddresses(rootCtx, checkpoints, chainContracts); err != nil {
+			return fmt.Errorf("failed to load discovered addresses for chain %s: %v", chainName, err)
+		}
+		chain := NewChain(chainName, config.Chains[chainName], chainContracts, a.logger, outputs, checkpoints, checkpoint)
 		chainServices = append(chainServices, chain)
 	}
 
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// scansBlocks tells whether every block has to be fetched, not only the ones with logs.
+func (c *chain) scansBlocks() bool {
+	return c.emitBlocks || c.hasCalls
+}
+
+// scanBlocks fetches every block up to the confirmed block to emit block records and
+// decode calls. In optimistic mode the confirmed block can be behind the last processed
+// block, so the scan keeps its own position.
+func (c *chain) scanBlocks(ctx context.Context, client *chainClient) error {
+	toBlockNumber := c.confirmedNumber
+	if toBlockNumber > c.lastBlockNumber {
+		toBlockNumber = c.lastBlockNumber
+	}
+	if toBlockNumber <= c.scannedBlockNumber {
+		return nil
+	}
+	fromBlockNumber := c.scannedBlockNumber + 1
+
+	// Full transactions are needed to find calls, while traces refer to them by hash.
+	fullTransactions := c.hasCalls && c.tracer == ""
+	blocks, err := client.BlocksByNumber(ctx, fromBlockNumber, toBlockNumber, fullTransactions)
+	if err != nil {
+		return fmt.Errorf("call to BlocksByNumber failed: %w", err)
+	}
+
+	// Scanned blocks are tracked in the reorg window, so that their calls and records can be retracted.
+	// Blocks that differ from the processed ones, or from each other, mean a reorg in between,
+	// the scan is retried.
+	for i, block := range blocks {
+		if (i > 0 && block.ParentHash != blocks[i-1].Hash) || !c.window.consistent(uint64(block.Number), block.Hash, block.ParentHash) {
+			c.logger.Warnw("Scanned blocks are inconsistent with the processed ones (can be a reorg), retrying", "blockNumber", uint64(block.Number))
+			return nil
+		}
+	}
+	tracked := make([]*reorgBlock, len(blocks))
+	for i, block := range blocks {
+		tracked[i] = c.window.track(uint64(block.Number), block.Hash)
+	}
+
+	if c.hasCalls {
+		if err := c.scanCalls(ctx, client, blocks, tracked); err != nil {
+			return err
+		}
+	}
+	if c.emitBlocks {
+		for i, block := range blocks {
+			record := c.outputBlock(block)
+			if tracked[i] != nil {
+				tracked[i].record = record
+			}
+		}
+	}
+
+	c.scannedBlockNumber = toBlockNumber
+	return nil
+}
+
+func (c *chain) outputBlock(block *rpcBlock) *types.Block {
+	record := &types.Block{
+		ChainName:  c.name,
+		ChainID:    c.chainID,
+		Number:     uint64(block.Number),
+		Hash:       block.Hash,
+		ParentHash: block.ParentHash,
+		BlockTs:    time.Unix(int64(block.Timestamp), 0),
+		GasUsed:    uint64(block.GasUsed),
+		GasLimit:   uint64(block.GasLimit),
+		BaseFee:    (*big.Int)(block.BaseFee),
+		TxCount:    len(block.Transactions),
+		Miner:      block.Miner,
+	}
+	common.PromBlockNumber.WithLabelValues(c.name).Set(float64(record.Number))
+	if record.BaseFee != nil {
+		baseFee, _ := new(big.Float).SetInt(record.BaseFee).Float64()
+		common.PromBaseFee.WithLabelValues(c.name).Set(baseFee)
+	}
+	c.outputs.WriteBlock(record)
+	return record
+}
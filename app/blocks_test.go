@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/types"
+	"go.uber.org/zap"
+)
+
+func TestScanCheckpoint(t *testing.T) {
+	tests := []struct {
+		name            string
+		forkNumber      uint64
+		confirmedNumber uint64
+		wantScanned     uint64
+		wantNumber      uint64
+		wantHash        ethcommon.Hash
+	}{
+		{"scanned", 100, 14, 14, 14, testHash(14, 0)},
+		{"behind the confirmed block", 100, 12, 12, 12, testHash(12, 0)},
+		{"inconsistent", 12, 14, 11, 11, testHash(11, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeRPC(t, headersRPC(t, tt.forkNumber))
+			defer server.Close()
+
+			ctx := context.Background()
+			client, err := dialChainClient(ctx, server.URL)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer client.Close()
+
+			checkpoints := NewFileCheckpoints(filepath.Join(t.TempDir(), "checkpoints.json"))
+			c := &chain{
+				name:               "test",
+				logger:             zap.NewNop().Sugar(),
+				outputs:            types.Outputs{&testOutput{}},
+				checkpoints:        checkpoints,
+				confirmedNumber:    tt.confirmedNumber,
+				lastBlockNumber:    14,
+				lastBlockHash:      testHash(14, 0),
+				window:             newReorgWindow(8),
+				emitBlocks:         true,
+				scannedBlockNumber: 11,
+			}
+			for number := uint64(10); number <= 14; number++ {
+				c.window.add(number, testHash(number, 0), number <= tt.confirmedNumber)
+			}
+
+			if err := c.scanBlocks(ctx, client); err != nil {
+				t.Fatalf("scanBlocks: %v", err)
+			}
+			if c.scannedBlockNumber != tt.wantScanned {
+				t.Errorf("scanned block = %d, want %d", c.scannedBlockNumber, tt.wantScanned)
+			}
+
+			// The checkpoint never passes the scanned block, so that a restart scans the rest.
+			c.saveCheckpoint(ctx)
+			checkpoint, err := checkpoints.LoadCheckpoint(ctx, "test")
+			if err != nil || checkpoint == nil {
+				t.Fatalf("checkpoint: %v, %v", checkpoint, err)
+			}
+			if checkpoint.BlockNumber != tt.wantNumber || checkpoint.BlockHash != tt.wantHash {
+				t.Errorf("checkpoint = %d %s, want %d %s", checkpoint.BlockNumber, checkpoint.BlockHash, tt.wantNumber, tt.wantHash)
+			}
+		})
+	}
+}
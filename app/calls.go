@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// callFrame is a call to a contract: either a transaction, or an internal call taken from a trace.
+type callFrame struct {
+	txHash   ethcommon.Hash
+	txIndex  uint
+	callType string
+	// traceAddress is the path of the frame in the call tree, e.g. "0.1", empty for the transaction itself.
+	traceAddress string
+	from         ethcommon.Address
+	to           ethcommon.Address
+	value        *big.Int
+	input        []byte
+	success      bool
+}
+
+// hasCallContracts tells whether any contract of the chain has calls decoding enabled.
+func hasCallContracts(contracts []types.Contract) bool {
+	for _, contract := range contracts {
+		if contract.HasCalls() {
+			return true
+		}
+	}
+	return false
+}
+
+// scanCalls decodes calls to the addresses of contracts with calls enabled: transactions,
+// or with a tracer configured, all calls including internal ones. Calls are kept in the tracked blocks.
+func (c *chain) scanCalls(ctx context.Context, client *chainClient, blocks []*rpcBlock, tracked []*reorgBlock) error {
+	var frames [][]callFrame
+	var err error
+	if c.tracer != "" {
+		frames, err = client.TraceBlocks(ctx, c.tracer, blocks)
+		if err != nil {
+			return fmt.Errorf("call to TraceBlocks failed: %w", err)
+		}
+	} else {
+		frames, err = c.transactionFrames(ctx, client, blocks)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, block := range blocks {
+		for j := range frames[i] {
+			frame := &frames[i][j]
+			if contract := c.callContract(frame); contract != nil {
+				if call := c.decodeAndOutputCall(block, frame, contract); call != nil && tracked[i] != nil {
+					tracked[i].calls = append(tracked[i].calls, call)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// callContract returns the contract decoding the frame: the called contract, if it has calls
+// of the frame type enabled. Created contracts are the callee of their creation frames.
+func (c *chain) callContract(frame *callFrame) types.Contract {
+	contract, exists := c.addressMap[frame.to]
+	if !exists || !contract.HasCalls() || !contract.HasCallType(frame.callType) {
+		return nil
+	}
+	return contract
+}
+
+// transactionFrames returns transactions sent to contracts with calls enabled, with their status from receipts.
+func (c *chain) transactionFrames(ctx context.Context, client *chainClient, blocks []*rpcBlock) ([][]callFrame, error) {
+	frames := make([][]callFrame, len(blocks))
+	var txHashes []ethcommon.Hash
+	for i, block := range blocks {
+		for _, tx := range block.Transactions {
+			if tx.To == nil {
+				continue
+			}
+			frame := callFrame{
+				txHash:   tx.Hash,
+				txIndex:  uint(tx.TransactionIndex),
+				callType: CallTypeCall,
+				from:     tx.From,
+				to:       *tx.To,
+				value:    (*big.Int)(tx.Value),
+				input:    tx.Input,
+			}
+			if c.callContract(&frame) != nil {
+				frames[i] = append(frames[i], frame)
+				txHashes = append(txHashes, tx.Hash)
+			}
+		}
+	}
+	if len(txHashes) == 0 {
+		return frames, nil
+	}
+
+	receipts, err := client.ReceiptsByHash(ctx, txHashes)
+	if err != nil {
+		return nil, fmt.Errorf("call to ReceiptsByHash failed: %w", err)
+	}
+	for i, block := range blocks {
+		for j := range frames[i] {
+			frame := &frames[i][j]
+			receipt := receipts[frame.txHash]
+			if receipt.BlockHash != block.Hash {
+				return nil, fmt.Errorf("transaction %s receipt is not in block %d", frame.txHash, uint64(block.Number))
+			}
+			frame.success = uint64(receipt.Status) == ethtypes.ReceiptStatusSuccessful
+		}
+	}
+	return frames, nil
+}
+
+func (c chain) decodeAndOutputCall(block *rpcBlock, frame *callFrame, contract types.Contract) *types.Call {
+	call, err := decodeCall(time.Unix(int64(block.Timestamp), 0), block, frame, contract)
+	if err != nil {
+		common.PromCallsMalformed.WithLabelValues(c.name, contract.Name()).Inc()
+		c.logger.Warnw("Could not decode call", "contractName", contract.Name(), "txHash", frame.txHash, "traceAddress", frame.traceAddress, "err", err)
+		return nil
+	}
+	call.ChainID = c.chainID
+	encodeNumbers(call.MethodArgs, contract.NumberEncoding())
+	if c.mempool != nil {
+		c.mempool.included(call.TxHash, call.BlockTs)
+	}
+	common.PromCalls.WithLabelValues(c.name, contract.Name(), call.MethodName).Inc()
+	c.outputs.WriteCall(call)
+	return call
+}
@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/jpillora/backoff"
 	"github.com/pinebit/lognite/app/common"
 	"github.com/pinebit/lognite/app/types"
@@ -22,66 +22,158 @@ type Chain interface {
 }
 
 type chain struct {
-	name            string
-	rpc             string
-	contracts       []types.Contract
-	addresses       []ethcommon.Address
-	addressMap      map[ethcommon.Address]types.Contract
-	logger          *zap.SugaredLogger
-	outputs         types.Outputs
-	confirmations   uint
-	lastBlockNumber uint64
-	lastBlockHash   ethcommon.Hash
+	name               string
+	endpoints          *endpoints
+	contracts          []types.Contract
+	addresses          []ethcommon.Address
+	addressMap         map[ethcommon.Address]types.Contract
+	factories          map[string][]types.Contract
+	queries            []contractQuery
+	logger             *zap.SugaredLogger
+	outputs            types.Outputs
+	checkpoints        types.Checkpoints
+	confirmations      uint
+	finality           string
+	chainID            uint64
+	optimistic         bool
+	confirmedNumber    uint64
+	startBlockNumber   uint64
+	lastBlockNumber    uint64
+	lastBlockHash      ethcommon.Hash
+	blockRange         uint64
+	maxBlockRange      uint64
+	window             *reorgWindow
+	txCache            *txCache
+	pollInterval       time.Duration
+	hasCalls           bool
+	emitBlocks         bool
+	scannedBlockNumber uint64
+	tracer             string
+	hasState           bool
+	statePolledAt      map[string]uint64
+	mempool            *mempool
+	// discovered are addresses found in factory events of blocks that are not processed yet.
+	discovered []*discoveredAddress
 }
 
 var (
-	zeroHash = ethcommon.HexToHash("0x0")
+	zeroHash           = ethcommon.HexToHash("0x0")
+	errChainIDMismatch = errors.New("chain ID mismatch")
 )
 
-func NewChain(chainName string, config ChainConfig, contracts []types.Contract, logger *zap.SugaredLogger, outputs types.Outputs) Chain {
+func NewChain(chainName string, config ChainConfig, contracts []types.Contract, logger *zap.SugaredLogger, outputs types.Outputs, checkpoints types.Checkpoints, checkpoint *types.Checkpoint) Chain {
 	var addresses []ethcommon.Address
+	var startBlockNumber uint64
 	addressMap := make(map[ethcommon.Address]types.Contract)
+	factories := make(map[string][]types.Contract)
+	var queries []contractQuery
 
 	for _, contract := range contracts {
+		if hasContractQueries(contract) {
+			queries = append(queries, newContractQueries(contract)...)
+		}
+		if contract.Factory() != nil {
+			factories[contract.Factory().ContractName] = append(factories[contract.Factory().ContractName], contract)
+		}
 		for _, address := range contract.Addresses() {
-			addresses = append(addresses, address)
+			if !hasContractQueries(contract) {
+				addresses = append(addresses, address)
+			}
 			addressMap[address] = contract
 		}
+		// The chain backfills from the earliest start block among its contracts.
+		if contract.StartBlock() > 0 && (startBlockNumber == 0 || contract.StartBlock() < startBlockNumber) {
+			startBlockNumber = contract.StartBlock()
+		}
+	}
+
+	c := &chain{
+		name:             chainName,
+		logger:           logger.Named(chainName),
+		endpoints:        newEndpoints(chainName, config.RPCs),
+		contracts:        contracts,
+		addresses:        addresses,
+		addressMap:       addressMap,
+		factories:        factories,
+		queries:          queries,
+		outputs:          outputs,
+		checkpoints:      checkpoints,
+		confirmations:    config.Confirmations,
+		finality:         config.Finality,
+		chainID:          config.ChainID,
+		optimistic:       config.Optimistic,
+		startBlockNumber: startBlockNumber,
+		blockRange:       config.MaxBlockRange,
+		maxBlockRange:    config.MaxBlockRange,
+		window:           newReorgWindow(common.DefaultReorgWindow),
+		txCache:          newTxCache(common.DefaultReorgWindow),
+		pollInterval:     config.PollInterval,
+		hasCalls:         hasCallContracts(contracts),
+		emitBlocks:       config.Blocks,
+		tracer:           config.Tracer,
+		hasState:         hasStateContracts(contracts),
+		statePolledAt:    make(map[string]uint64),
+	}
+
+	if config.Mempool {
+		c.mempool = newMempool(chainName)
+		for _, contract := range contracts {
+			if contract.HasCalls() && contract.HasCallType(CallTypeCall) {
+				for _, address := range contract.Addresses() {
+					c.mempool.watch(address, contract)
+				}
+			}
+		}
 	}
 
-	return &chain{
-		name:          chainName,
-		logger:        logger.Named(chainName),
-		rpc:           config.RPC,
-		contracts:     contracts,
-		addresses:     addresses,
-		addressMap:    addressMap,
-		outputs:       outputs,
-		confirmations: config.Confirmations,
+	// A checkpoint takes precedence over start blocks: resume right after the last processed block.
+	if checkpoint != nil {
+		c.logger.Infow("Resuming from checkpoint", "blockNumber", checkpoint.BlockNumber, "blockHash", checkpoint.BlockHash)
+		c.lastBlockNumber = checkpoint.BlockNumber
+		c.lastBlockHash = checkpoint.BlockHash
+		c.window.add(checkpoint.BlockNumber, checkpoint.BlockHash, true)
 	}
+
+	return c
 }
 
-func (c chain) Run(ctx context.Context, done func()) {
+func (c *chain) Run(ctx context.Context, done func()) {
 	defer done()
 
 	backoff := backoff.Backoff{}
 
+	go c.endpoints.probe(ctx, c.logger)
+
 	for {
 		common.PromReConnections.WithLabelValues(c.name).Inc()
 
-		client, err := ethclient.DialContext(ctx, c.rpc)
+		endpoint := c.endpoints.best()
+		client, err := dialChainClient(ctx, endpoint.url)
 		if err != nil {
-			c.logger.Errorw("Failed to connect RPC", "url", c.rpc)
+			c.logger.Errorw("Failed to connect RPC", "url", endpoint.url)
+			c.endpoints.reportFailure(endpoint)
 		} else {
-			c.logger.Debugw("RPC connected", "url", c.rpc)
+			c.logger.Debugw("RPC connected", "url", endpoint.url)
 			common.PromConnections.WithLabelValues(c.name).Inc()
-			backoff.Reset()
+			c.endpoints.setActive(endpoint)
 
-			c.receiveLoop(ctx, client)
-		}
+			if err := c.verifyChainID(ctx, client); err != nil {
+				c.logger.Errorw("Refusing to ingest from RPC", "url", endpoint.url, "err", err)
+				if errors.Is(err, errChainIDMismatch) {
+					c.endpoints.disable(endpoint)
+				} else {
+					c.endpoints.reportFailure(endpoint)
+				}
+			} else {
+				backoff.Reset()
+				if !c.receiveLoop(ctx, client, endpoint) {
+					c.endpoints.reportFailure(endpoint)
+				}
+			}
 
-		client.Close()
-		common.PromConnections.WithLabelValues(c.name).Dec()
+			client.Close()
+			common.PromConnections.WithLabelValues(c.name).Dec()
+		}
 
 		if errors.Is(ctx.Err(), context.Canceled) {
 			return
@@ -91,36 +183,97 @@ func (c chain) Run(ctx context.Context, done func()) {
 	}
 }
 
-func (c *chain) receiveLoop(ctx context.Context, client *ethclient.Client) {
-	headersCh := make(chan *ethtypes.Header)
-	sub, err := client.SubscribeNewHead(ctx, headersCh)
+// verifyChainID checks the RPC serves the configured chain. Without a configured
+// chain ID, the first one reported by the RPC is expected from the other endpoints.
+func (c *chain) verifyChainID(ctx context.Context, client *chainClient) error {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("call to ChainID failed: %w", err)
+	}
+	if c.chainID == 0 {
+		c.chainID = chainID.Uint64()
+	} else if chainID.Uint64() != c.chainID {
+		return fmt.Errorf("%w: expected %d, got %d", errChainIDMismatch, c.chainID, chainID.Uint64())
+	}
+	return nil
+}
+
+// receiveLoop ingests logs until an error occurs or a better endpoint becomes available,
+// the latter is the only case it returns true.
+func (c *chain) receiveLoop(ctx context.Context, client *chainClient, endpoint *endpoint) bool {
+	heads, err := newHeadTracker(ctx, client, endpoint.url, c.pollInterval)
 	if err != nil {
 		c.logger.Errorw("Call to SubscribeNewHead failed, will reconnect", "err", err)
-		return
+		return false
+	}
+	defer heads.Close()
+
+	if c.mempool != nil {
+		if isHTTPURL(endpoint.url) {
+			c.logger.Warnw("Mempool monitoring requires a websocket RPC endpoint", "endpoint", endpoint.label)
+		} else {
+			mempoolCtx, cancelMempool := context.WithCancel(ctx)
+			defer cancelMempool()
+			go c.watchMempool(mempoolCtx, client)
+		}
 	}
-	defer sub.Unsubscribe()
 
 	var stopAtBlockNumber uint64
 	timer := time.NewTimer(common.DefaultBackfillInterval)
+	failoverTicker := time.NewTicker(common.DefaultProbeInterval)
+	defer failoverTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			return
-		case subErr := <-sub.Err():
-			c.logger.Errorw("Logs subscription error, will reconnect", "err", subErr)
-			return
-		case header := <-headersCh:
-			stopAtBlockNumber = header.Number.Uint64() - uint64(c.confirmations)
+			return true
+		case headErr := <-heads.Err():
+			c.logger.Errorw("Head tracking error, will reconnect", "err", headErr)
+			return false
+		case head := <-heads.Heads():
+			c.endpoints.reportHead(endpoint, head)
+			common.PromHeadBlock.WithLabelValues(c.name).Set(float64(head))
+			confirmedBlockNumber, err := c.confirmedBlockNumber(ctx, client, head)
+			if err != nil {
+				c.logger.Errorw("Failed to get confirmed block, will reconnect", "finality", c.finality, "err", err)
+				return false
+			}
+			c.confirmedNumber = confirmedBlockNumber
+			stopAtBlockNumber = confirmedBlockNumber
+			if c.hasState {
+				if err := c.pollState(ctx, client); err != nil {
+					c.logger.Errorw("Failed to poll state, will reconnect", "blockNumber", confirmedBlockNumber, "err", err)
+					return false
+				}
+			}
+			if c.optimistic {
+				// Events are emitted at head as pending, and confirmed once they get past the confirmed block.
+				stopAtBlockNumber = head
+				c.confirmEvents(ctx)
+			}
 			if c.lastBlockNumber == 0 {
-				c.lastBlockNumber = stopAtBlockNumber - 1
+				if c.startBlockNumber > 0 && c.startBlockNumber <= stopAtBlockNumber {
+					c.logger.Infow("Backfilling history", "fromBlock", c.startBlockNumber, "toBlock", stopAtBlockNumber)
+					c.lastBlockNumber = c.startBlockNumber - 1
+				} else {
+					c.lastBlockNumber = stopAtBlockNumber - 1
+				}
+			}
+		case <-failoverTicker.C:
+			if best := c.endpoints.best(); best != endpoint {
+				c.logger.Warnw("Switching to a better RPC endpoint", "from", endpoint.label, "to", best.label)
+				return true
 			}
 		case <-timer.C:
 			if stopAtBlockNumber > c.lastBlockNumber {
-				nextBlockNumber := c.lastBlockNumber + 1
-				if err := c.getBlockLogs(ctx, client, nextBlockNumber); err != nil {
-					c.logger.Errorw("Failed pulling logs for block, will reconnect", "blockNumber", nextBlockNumber, "err", err)
-					return
+				if err := c.getRangeLogs(ctx, client, stopAtBlockNumber); err != nil {
+					if isRateLimitError(err) {
+						c.logger.Warnw("Rate limited by provider, backing off", "fromBlock", c.lastBlockNumber+1, "err", err)
+						timer.Reset(common.DefaultRateLimitBackoff)
+						continue
+					}
+					c.logger.Errorw("Failed pulling logs for block range, will reconnect", "fromBlock", c.lastBlockNumber+1, "err", err)
+					return false
 				}
 			}
 		}
@@ -130,53 +283,249 @@ func (c *chain) receiveLoop(ctx context.Context, client *ethclient.Client) {
 	}
 }
 
-func (c *chain) getBlockLogs(ctx context.Context, client *ethclient.Client, blockNumber uint64) error {
-	bigBlockNumber := big.NewInt(int64(blockNumber))
-	header, err := client.HeaderByNumber(ctx, bigBlockNumber)
+// confirmedBlockNumber returns the latest block events can be emitted for:
+// either the head minus confirmations, or the node's safe or finalized block.
+func (c *chain) confirmedBlockNumber(ctx context.Context, client *chainClient, head uint64) (uint64, error) {
+	var tag rpc.BlockNumber
+	switch c.finality {
+	case FinalitySafe:
+		tag = rpc.SafeBlockNumber
+	case FinalityFinalized:
+		tag = rpc.FinalizedBlockNumber
+	default:
+		return head - uint64(c.confirmations), nil
+	}
+	header, err := client.HeaderByNumber(ctx, big.NewInt(tag.Int64()))
 	if err != nil {
-		return fmt.Errorf("call to HeaderByNumber failed: %v", err)
-	}
-	if c.lastBlockHash != zeroHash && header.ParentHash != c.lastBlockHash {
-		common.PromReorgErrors.WithLabelValues(c.name).Inc()
-		c.logger.Errorw("Block parent hash mismatch (can be a reorg), try increasing confirmations", "parentHash", header.ParentHash)
-		return fmt.Errorf("forced to reconnect")
-	}
-	if c.lastBlockNumber != header.Number.Uint64()-1 {
-		common.PromReorgErrors.WithLabelValues(c.name).Inc()
-		c.logger.Errorw("Block number is out of order (can be a reorg), try increasing confirmations", "blockNumber", header.Number.Uint64())
-		return fmt.Errorf("forced to reconnect")
-	}
-	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
-		Addresses: c.addresses,
-		FromBlock: bigBlockNumber,
-		ToBlock:   bigBlockNumber,
-	})
+		return 0, fmt.Errorf("call to HeaderByNumber failed: %w", err)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// getRangeLogs pulls logs of the next block range in a single eth_getLogs call.
+// The range adapts to the provider: it shrinks when the provider rejects it as too large
+// and grows back after successful full-range calls. Headers are fetched only for
+// the blocks that contain logs and for the range boundaries.
+func (c *chain) getRangeLogs(ctx context.Context, client *chainClient, stopAtBlockNumber uint64) error {
+	// The blocks scan starts along with the logs, and never stays ahead of them after a reorg.
+	if c.scansBlocks() && (c.scannedBlockNumber == 0 || c.scannedBlockNumber > c.lastBlockNumber) {
+		c.scannedBlockNumber = c.lastBlockNumber
+	}
+
+	fromBlockNumber := c.lastBlockNumber + 1
+	toBlockNumber := stopAtBlockNumber
+	if toBlockNumber-fromBlockNumber+1 > c.blockRange {
+		toBlockNumber = fromBlockNumber + c.blockRange - 1
+	}
+
+	logs, err := c.filterLogs(ctx, client, fromBlockNumber, toBlockNumber)
 	if err != nil {
-		return fmt.Errorf("call to FilterLogs failed: %v", err)
+		if c.blockRange > 1 && isBlockRangeError(err) {
+			c.blockRange /= 2
+			c.logger.Debugw("Block range rejected by provider, shrinking", "blockRange", c.blockRange, "err", err)
+			return nil
+		}
+		return fmt.Errorf("call to FilterLogs failed: %w", err)
+	}
+
+	var blockNumbers []uint64
+	blockNumbersSet := make(map[uint64]struct{})
+	for _, blockNumber := range []uint64{fromBlockNumber, toBlockNumber} {
+		if _, exists := blockNumbersSet[blockNumber]; !exists {
+			blockNumbersSet[blockNumber] = struct{}{}
+			blockNumbers = append(blockNumbers, blockNumber)
+		}
 	}
 	for _, log := range logs {
-		if log.Removed {
-			common.PromReorgErrors.WithLabelValues(c.name).Inc()
-			c.logger.Errorw("Ignoring unexpected removed log, consider increasing confirmations", "tx_hash", log.TxHash, "tx_index", log.TxIndex)
-		} else {
-			c.decodeAndOutputLog(&log, header.Time)
+		if _, exists := blockNumbersSet[log.BlockNumber]; !exists {
+			blockNumbersSet[log.BlockNumber] = struct{}{}
+			blockNumbers = append(blockNumbers, log.BlockNumber)
 		}
 	}
-	c.lastBlockNumber = blockNumber
-	c.lastBlockHash = header.Hash()
+	sort.Slice(blockNumbers, func(i, j int) bool { return blockNumbers[i] < blockNumbers[j] })
+	headers, err := client.HeadersByNumber(ctx, blockNumbers)
+	if err != nil {
+		return fmt.Errorf("call to HeadersByNumber failed: %w", err)
+	}
+
+	if c.lastBlockHash != zeroHash && headers[fromBlockNumber].ParentHash != c.lastBlockHash {
+		c.logger.Warnw("Block parent hash mismatch, handling reorg", "blockNumber", fromBlockNumber, "parentHash", headers[fromBlockNumber].ParentHash)
+		return c.handleReorg(ctx, client)
+	}
+
+	// Logs and headers are fetched by separate calls, a reorg in between makes them inconsistent.
+	// The range is retried on the next tick, and the parent hash check above catches the reorg.
+	for _, log := range logs {
+		if log.Removed || log.BlockHash != headers[log.BlockNumber].Hash {
+			c.logger.Warnw("Logs are inconsistent with headers (can be a reorg), retrying", "blockNumber", log.BlockNumber)
+			return nil
+		}
+	}
+
+	txs, err := c.getTransactions(ctx, client, logs)
+	if err != nil {
+		return fmt.Errorf("call to TransactionsByHash failed: %w", err)
+	}
+
+	for _, blockNumber := range blockNumbers {
+		c.window.add(blockNumber, headers[blockNumber].Hash, blockNumber <= c.confirmedNumber)
+	}
+	c.trackDiscoveredAddresses(ctx, headers)
+	for _, log := range logs {
+		var tx *types.Transaction
+		if log.contract.IsEnriched() {
+			tx = txs[log.TxHash]
+		}
+		if event := c.decodeAndOutputLog(&log.Log, log.contract, uint64(headers[log.BlockNumber].Timestamp), tx); event != nil {
+			c.window.addEvent(event)
+		}
+	}
+
+	if toBlockNumber-fromBlockNumber+1 == c.blockRange && c.blockRange < c.maxBlockRange {
+		c.blockRange *= 2
+		if c.blockRange > c.maxBlockRange {
+			c.blockRange = c.maxBlockRange
+		}
+	}
+
+	c.lastBlockNumber = toBlockNumber
+	c.lastBlockHash = headers[toBlockNumber].Hash
+	c.window.prune(c.lastBlockNumber)
+	c.txCache.prune(c.lastBlockNumber)
+	if c.scansBlocks() {
+		if err := c.scanBlocks(ctx, client); err != nil {
+			return err
+		}
+	}
+	c.saveCheckpoint(ctx)
 	return nil
 }
 
-func (c chain) decodeAndOutputLog(log *ethtypes.Log, timestamp uint64) {
-	contract := c.addressMap[log.Address]
+// getTransactions fetches transactions of the logs of enriched contracts, each transaction once
+// per block: transactions already fetched for the same block hash are taken from the cache.
+func (c *chain) getTransactions(ctx context.Context, client *chainClient, logs []contractLog) (map[ethcommon.Hash]*types.Transaction, error) {
+	txs := make(map[ethcommon.Hash]*types.Transaction)
+	blockHashes := make(map[ethcommon.Hash]ethcommon.Hash)
+	blockNumbers := make(map[ethcommon.Hash]uint64)
+	for _, log := range logs {
+		if !log.contract.IsEnriched() {
+			continue
+		}
+		if tx := c.txCache.get(log.BlockHash, log.TxHash); tx != nil {
+			txs[log.TxHash] = tx
+			continue
+		}
+		blockHashes[log.TxHash] = log.BlockHash
+		blockNumbers[log.TxHash] = log.BlockNumber
+	}
+	if len(blockHashes) == 0 {
+		return txs, nil
+	}
+	fetched, err := client.TransactionsByHash(ctx, blockHashes)
+	if err != nil {
+		return nil, err
+	}
+	for txHash, tx := range fetched {
+		c.txCache.add(blockNumbers[txHash], blockHashes[txHash], txHash, tx)
+		txs[txHash] = tx
+	}
+	return txs, nil
+}
+
+// confirmEvents emits confirmations for pending events of the blocks that got past the confirmed block.
+func (c *chain) confirmEvents(ctx context.Context) {
+	confirmedBlocks := c.window.confirm(c.confirmedNumber)
+	for _, block := range confirmedBlocks {
+		for _, event := range block.events {
+			common.PromEventsConfirmed.WithLabelValues(c.name, event.Contract.Name()).Inc()
+			c.outputs.Confirm(event)
+		}
+		for _, rawLog := range block.rawLogs {
+			c.writeRawLog(rawLog)
+		}
+		for _, discovered := range block.addresses {
+			c.saveAddress(ctx, discovered)
+		}
+	}
+	if len(confirmedBlocks) > 0 {
+		c.saveCheckpoint(ctx)
+	}
+}
+
+// saveCheckpoint persists the last processed block. In optimistic mode it is the last
+// confirmed block instead, so pending events are re-ingested after a restart.
+func (c *chain) saveCheckpoint(ctx context.Context) {
+	checkpoint := types.Checkpoint{
+		BlockNumber: c.lastBlockNumber,
+		BlockHash:   c.lastBlockHash,
+	}
+	if c.optimistic {
+		block := c.window.lastConfirmed()
+		if block == nil {
+			return
+		}
+		checkpoint.BlockNumber = block.number
+		checkpoint.BlockHash = block.hash
+	}
+	// The scan resumes from the checkpoint, so it must not skip blocks that are not scanned yet:
+	// the scan stays at the confirmed block, and is retried when the blocks were inconsistent.
+	// Logs of the blocks in between are processed again after a restart.
+	if c.scansBlocks() && c.scannedBlockNumber < checkpoint.BlockNumber {
+		block := c.window.find(c.scannedBlockNumber)
+		if block == nil {
+			return
+		}
+		checkpoint.BlockNumber = block.number
+		checkpoint.BlockHash = block.hash
+	}
+	if err := c.checkpoints.SaveCheckpoint(ctx, c.name, checkpoint); err != nil {
+		c.logger.Errorw("Failed to save checkpoint", "blockNumber", checkpoint.BlockNumber, "err", err)
+	}
+}
+
+func (c chain) decodeAndOutputLog(log *ethtypes.Log, contract types.Contract, timestamp uint64, tx *types.Transaction) *types.Event {
+	if log.BlockNumber < contract.StartBlock() {
+		return nil
+	}
 	common.PromLogsReceived.WithLabelValues(c.name, contract.Name()).Inc()
 	blockTs := time.Unix(int64(timestamp), 0)
 	event, err := decodeEvent(blockTs, log, contract)
+	if event != nil {
+		event.ChainID = c.chainID
+		event.Tx = tx
+		if log.BlockNumber > c.confirmedNumber {
+			event.Status = types.EventPending
+		}
+	}
 	if err != nil {
 		common.PromEventsMalformed.WithLabelValues(c.name, contract.Name()).Inc()
-		c.logger.Warnw("Could not decode event", "err", err)
+		if contract.IsWildcard() {
+			// Wildcard filters match by signature only, so logs of unrelated contracts are expected.
+			c.logger.Debugw("Could not decode event", "contractName", contract.Name(), "address", log.Address, "err", err)
+		} else {
+			c.logger.Warnw("Could not decode event", "err", err)
+		}
+		if contract.HasRawLogs() {
+			c.outputRawLog(log, contract, blockTs, err)
+		}
+		return nil
 	} else if event != nil {
+		if predicate := contract.Predicate(event.EventName); predicate != nil {
+			matched, err := predicate.Match(event.EventArgs)
+			if err != nil {
+				c.logger.Warnw("Could not evaluate event filter", "contractName", contract.Name(), "eventName", event.EventName, "err", err)
+			}
+			if !matched {
+				common.PromEventsFiltered.WithLabelValues(c.name, contract.Name(), event.EventName).Inc()
+				return nil
+			}
+		}
+		if c.mempool != nil {
+			c.mempool.included(log.TxHash, blockTs)
+		}
+		encodeNumbers(event.EventArgs, contract.NumberEncoding())
 		common.PromEvents.WithLabelValues(c.name, contract.Name(), event.EventName).Inc()
 		c.outputs.Write(event)
 	}
+	return event
 }
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+type fileCheckpointsData struct {
+	Checkpoints map[string]types.Checkpoint               `json:"checkpoints"`
+	Addresses   map[string]map[string][]ethcommon.Address `json:"addresses"`
+}
+
+type fileCheckpoints struct {
+	path string
+	mu   sync.Mutex
+	data *fileCheckpointsData
+}
+
+// NewFileCheckpoints returns a checkpoint store backed by a local JSON file,
+// used when no Postgres output is configured.
+func NewFileCheckpoints(path string) types.Checkpoints {
+	return &fileCheckpoints{
+		path: path,
+	}
+}
+
+func (f *fileCheckpoints) LoadCheckpoint(ctx context.Context, chainName string) (*types.Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return nil, err
+	}
+	checkpoint, exists := f.data.Checkpoints[chainName]
+	if !exists {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (f *fileCheckpoints) SaveCheckpoint(ctx context.Context, chainName string, checkpoint types.Checkpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return err
+	}
+	f.data.Checkpoints[chainName] = checkpoint
+	return f.write()
+}
+
+func (f *fileCheckpoints) LoadAddresses(ctx context.Context, chainName, contractName string) ([]ethcommon.Address, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return nil, err
+	}
+	return f.data.Addresses[chainName][contractName], nil
+}
+
+func (f *fileCheckpoints) SaveAddress(ctx context.Context, chainName, contractName string, address ethcommon.Address) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return err
+	}
+	if f.data.Addresses[chainName] == nil {
+		f.data.Addresses[chainName] = make(map[string][]ethcommon.Address)
+	}
+	f.data.Addresses[chainName][contractName] = append(f.data.Addresses[chainName][contractName], address)
+	return f.write()
+}
+
+func (f *fileCheckpoints) RemoveAddress(ctx context.Context, chainName, contractName string, address ethcommon.Address) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.read(); err != nil {
+		return err
+	}
+	var kept []ethcommon.Address
+	for _, a := range f.data.Addresses[chainName][contractName] {
+		if a != address {
+			kept = append(kept, a)
+		}
+	}
+	if f.data.Addresses[chainName] != nil {
+		f.data.Addresses[chainName][contractName] = kept
+	}
+	return f.write()
+}
+
+func (f *fileCheckpoints) read() error {
+	if f.data != nil {
+		return nil
+	}
+	data := &fileCheckpointsData{}
+	raw, err := os.ReadFile(f.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, data); err != nil {
+			return err
+		}
+	}
+	if data.Checkpoints == nil {
+		data.Checkpoints = make(map[string]types.Checkpoint)
+	}
+	if data.Addresses == nil {
+		data.Addresses = make(map[string]map[string][]ethcommon.Address)
+	}
+	f.data = data
+	return nil
+}
+
+func (f *fileCheckpoints) write() error {
+	raw, err := json.MarshalIndent(f.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a crash never leaves a truncated file behind.
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, f.path)
+}
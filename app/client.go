@@ -0,0 +1,307 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// chainClient extends ethclient with batched calls over the underlying RPC client.
+type chainClient struct {
+	*ethclient.Client
+	rpcClient *rpc.Client
+}
+
+func dialChainClient(ctx context.Context, url string) (*chainClient, error) {
+	rpcClient, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &chainClient{
+		Client:    ethclient.NewClient(rpcClient),
+		rpcClient: rpcClient,
+	}, nil
+}
+
+// HeadersByNumber fetches headers of the given blocks in batches. Block hashes are the ones
+// reported by the node, as ethtypes.Header cannot recompute them for blocks of newer forks.
+func (c *chainClient) HeadersByNumber(ctx context.Context, blockNumbers []uint64) (map[uint64]*rpcBlock, error) {
+	batch := make([]rpc.BatchElem, len(blockNumbers))
+	results := make([]*rpcBlock, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeUint64(blockNumber), false},
+			Result: &results[i],
+		}
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	headers := make(map[uint64]*rpcBlock, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		if results[i] == nil {
+			return nil, fmt.Errorf("block %d: %v", blockNumber, ethereum.NotFound)
+		}
+		headers[blockNumber] = results[i]
+	}
+	return headers, nil
+}
+
+// rpcTransaction and rpcReceipt hold the fields used for enrichment. The raw JSON is
+// decoded instead of ethtypes, which rejects transaction types of some L2 chains.
+type rpcTransaction struct {
+	Hash             ethcommon.Hash     `json:"hash"`
+	TransactionIndex hexutil.Uint       `json:"transactionIndex"`
+	From             ethcommon.Address  `json:"from"`
+	To               *ethcommon.Address `json:"to"`
+	Value            *hexutil.Big       `json:"value"`
+	Input            hexutil.Bytes      `json:"input"`
+}
+
+type rpcReceipt struct {
+	BlockHash         ethcommon.Hash `json:"blockHash"`
+	Status            hexutil.Uint64 `json:"status"`
+	GasUsed           hexutil.Uint64 `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
+}
+
+// UnmarshalJSON accepts a transaction hash as well, which blocks without full transactions contain.
+func (t *rpcTransaction) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &t.Hash)
+	}
+	type transaction rpcTransaction
+	return json.Unmarshal(data, (*transaction)(t))
+}
+
+type rpcBlock struct {
+	Hash         ethcommon.Hash    `json:"hash"`
+	ParentHash   ethcommon.Hash    `json:"parentHash"`
+	Number       hexutil.Uint64    `json:"number"`
+	Timestamp    hexutil.Uint64    `json:"timestamp"`
+	GasUsed      hexutil.Uint64    `json:"gasUsed"`
+	GasLimit     hexutil.Uint64    `json:"gasLimit"`
+	BaseFee      *hexutil.Big      `json:"baseFeePerGas"`
+	Miner        ethcommon.Address `json:"miner"`
+	Transactions []rpcTransaction  `json:"transactions"`
+}
+
+// TransactionsByHash fetches transactions and their receipts in batches. Transactions
+// whose receipts are not in the expected block (e.g. after a reorg) are reported as an error.
+func (c *chainClient) TransactionsByHash(ctx context.Context, blockHashes map[ethcommon.Hash]ethcommon.Hash) (map[ethcommon.Hash]*types.Transaction, error) {
+	var txHashes []ethcommon.Hash
+	for txHash := range blockHashes {
+		txHashes = append(txHashes, txHash)
+	}
+
+	batch := make([]rpc.BatchElem, 0, 2*len(txHashes))
+	txs := make([]*rpcTransaction, len(txHashes))
+	receipts := make([]*rpcReceipt, len(txHashes))
+	for i, txHash := range txHashes {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getTransactionByHash",
+			Args:   []interface{}{txHash},
+			Result: &txs[i],
+		}, rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{txHash},
+			Result: &receipts[i],
+		})
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	result := make(map[ethcommon.Hash]*types.Transaction, len(txHashes))
+	for i, txHash := range txHashes {
+		if txs[i] == nil || receipts[i] == nil {
+			return nil, fmt.Errorf("transaction %s: %v", txHash, ethereum.NotFound)
+		}
+		if receipts[i].BlockHash != blockHashes[txHash] {
+			return nil, fmt.Errorf("transaction %s receipt is in block %s instead of %s", txHash, receipts[i].BlockHash, blockHashes[txHash])
+		}
+		tx := &types.Transaction{
+			From:              txs[i].From,
+			To:                txs[i].To,
+			Value:             (*big.Int)(txs[i].Value),
+			GasUsed:           uint64(receipts[i].GasUsed),
+			EffectiveGasPrice: (*big.Int)(receipts[i].EffectiveGasPrice),
+		}
+		if len(txs[i].Input) >= 4 {
+			tx.Selector = hexutil.Encode(txs[i].Input[:4])
+		}
+		result[txHash] = tx
+	}
+	return result, nil
+}
+
+// BlocksByNumber fetches blocks of the given range in batches, with full transactions
+// or with transaction hashes only.
+func (c *chainClient) BlocksByNumber(ctx context.Context, fromBlockNumber, toBlockNumber uint64, fullTransactions bool) ([]*rpcBlock, error) {
+	count := int(toBlockNumber - fromBlockNumber + 1)
+	batch := make([]rpc.BatchElem, count)
+	blocks := make([]*rpcBlock, count)
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeUint64(fromBlockNumber + uint64(i)), fullTransactions},
+			Result: &blocks[i],
+		}
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i, block := range blocks {
+		if block == nil {
+			return nil, fmt.Errorf("block %d: %v", fromBlockNumber+uint64(i), ethereum.NotFound)
+		}
+	}
+	return blocks, nil
+}
+
+// ReceiptsByHash fetches receipts of the given transactions in batches.
+func (c *chainClient) ReceiptsByHash(ctx context.Context, txHashes []ethcommon.Hash) (map[ethcommon.Hash]*rpcReceipt, error) {
+	batch := make([]rpc.BatchElem, len(txHashes))
+	receipts := make([]*rpcReceipt, len(txHashes))
+	for i, txHash := range txHashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{txHash},
+			Result: &receipts[i],
+		}
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	result := make(map[ethcommon.Hash]*rpcReceipt, len(txHashes))
+	for i, txHash := range txHashes {
+		if receipts[i] == nil {
+			return nil, fmt.Errorf("receipt %s: %v", txHash, ethereum.NotFound)
+		}
+		result[txHash] = receipts[i]
+	}
+	return result, nil
+}
+
+// PendingTransactions fetches the given transactions in batches, skipping the ones
+// that are no longer known to the node, e.g. dropped from the mempool.
+func (c *chainClient) PendingTransactions(ctx context.Context, txHashes []ethcommon.Hash) ([]*rpcTransaction, error) {
+	batch := make([]rpc.BatchElem, len(txHashes))
+	results := make([]*rpcTransaction, len(txHashes))
+	for i, txHash := range txHashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionByHash",
+			Args:   []interface{}{txHash},
+			Result: &results[i],
+		}
+	}
+	if err := c.sendBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	var txs []*rpcTransaction
+	for i := range batch {
+		if batch[i].Error == nil && results[i] != nil {
+			txs = append(txs, results[i])
+		}
+	}
+	return txs, nil
+}
+
+// CallContracts executes eth_call of the given messages at the block in batches.
+// Failures of individual calls, e.g. reverts, are returned per call.
+func (c *chainClient) CallContracts(ctx context.Context, msgs []ethereum.CallMsg, blockNumber uint64) ([]hexutil.Bytes, []error, error) {
+	batch := make([]rpc.BatchElem, len(msgs))
+	results := make([]hexutil.Bytes, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{map[string]interface{}{"to": msg.To, "data": hexutil.Bytes(msg.Data)}, hexutil.EncodeUint64(blockNumber)},
+			Result: &results[i],
+		}
+	}
+	if err := c.sendBatch(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+
+	errs := make([]error, len(msgs))
+	for i := range batch {
+		errs[i] = batch[i].Error
+	}
+	return results, errs, nil
+}
+
+// batchCall sends the batch and fails on the first element error.
+func (c *chainClient) batchCall(ctx context.Context, batch []rpc.BatchElem) error {
+	if err := c.sendBatch(ctx, batch); err != nil {
+		return err
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return elem.Error
+		}
+	}
+	return nil
+}
+
+// sendBatch sends the batch in chunks of DefaultRPCBatchSize.
+func (c *chainClient) sendBatch(ctx context.Context, batch []rpc.BatchElem) error {
+	for len(batch) > 0 {
+		batchSize := len(batch)
+		if batchSize > common.DefaultRPCBatchSize {
+			batchSize = common.DefaultRPCBatchSize
+		}
+		if err := c.rpcClient.BatchCallContext(ctx, batch[:batchSize]); err != nil {
+			return err
+		}
+		batch = batch[batchSize:]
+	}
+	return nil
+}
+
+// isBlockRangeError tells whether a provider rejected eth_getLogs because
+// the block range or the number of results was too large. Rate limit errors
+// often mention limits as well, they are not range errors.
+func isBlockRangeError(err error) bool {
+	if isRateLimitError(err) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, hint := range []string{"more than", "too many results", "too many logs", "too many blocks", "max results", "response size", "block range", "range is too", "range too", "too wide"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRateLimitError tells whether a provider throttled the request, e.g. HTTP 429
+// or exceeded request rate or compute units. Such requests are retried as is.
+func isRateLimitError(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, hint := range []string{"too many requests", "rate limit", "rate-limit", "ratelimit", "request rate", "compute units", "capacity exceeded", "daily request", "throughput"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		err        error
+		rateLimit  bool
+		blockRange bool
+	}{
+		// Rate limits, some of them mention limits or ranges as well.
+		{rpc.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429"}, true, false},
+		{fmt.Errorf("call to FilterLogs failed: %w", rpc.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429", Body: []byte("{}")}), true, false},
+		{errors.New("429 Too Many Requests"), true, false},
+		{errors.New("request rate exceeded"), true, false},
+		{errors.New("Your app has exceeded its compute units per second capacity"), true, false},
+		{errors.New("daily request count exceeded, request rate limited"), true, false},
+		{errors.New("rate limit exceeded for block range queries"), true, false},
+		{errors.New("project ID request rate exceeded"), true, false},
+
+		// Block range and result size limits.
+		{errors.New("query returned more than 10000 results"), false, true},
+		{errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), false, true},
+		{errors.New("block range is too wide"), false, true},
+		{errors.New("exceed maximum block range: 5000"), false, true},
+		{errors.New("too many blocks in range"), false, true},
+		{fmt.Errorf("call to FilterLogs failed: %w", errors.New("query timeout exceeded, max results reached")), false, true},
+
+		// Other errors are neither.
+		{rpc.HTTPError{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error"}, false, false},
+		{errors.New("header not found"), false, false},
+		{errors.New("gas limit exceeded"), false, false},
+		{context.DeadlineExceeded, false, false},
+	}
+	for _, tt := range tests {
+		if got := isRateLimitError(tt.err); got != tt.rateLimit {
+			t.Errorf("isRateLimitError(%q) = %v, want %v", tt.err, got, tt.rateLimit)
+		}
+		if got := isBlockRangeError(tt.err); got != tt.blockRange {
+			t.Errorf("isBlockRangeError(%q) = %v, want %v", tt.err, got, tt.blockRange)
+		}
+	}
+}
+
+func TestRateLimitedFilterLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := dialChainClient(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	err = fmt.Errorf("call to FilterLogs failed: %w", err)
+	if !isRateLimitError(err) || isBlockRangeError(err) {
+		t.Errorf("bare HTTP 429 is not classified as a rate limit: %v", err)
+	}
+}
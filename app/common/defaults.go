@@ -9,4 +9,18 @@ const (
 	DefaultPostgresPruneInterval time.Duration = 10 * time.Minute
 	DefaultConfirmations         uint          = 3
 	DefaultBackfillInterval      time.Duration = 100 * time.Millisecond
+	DefaultRateLimitBackoff      time.Duration = 2 * time.Second
+	DefaultCheckpointsFile       string        = "checkpoints.json"
+	DefaultMaxBlockRange         uint64        = 2000
+	DefaultRPCBatchSize          int           = 100
+	DefaultReorgWindow           uint64        = 256
+	DefaultPollInterval          time.Duration = 2 * time.Second
+	DefaultProbeInterval         time.Duration = 15 * time.Second
+	DefaultEndpointCooldown      time.Duration = time.Minute
+	DefaultEndpointDisabledFor   time.Duration = 10 * time.Minute
+	DefaultMaxEndpointLag        uint64        = 5
+	DefaultStatePollBlocks       uint64        = 10
+	DefaultMempoolInterval       time.Duration = time.Second
+	DefaultMempoolTTL            time.Duration = time.Hour
+	DefaultRedecodeBatchSize     int           = 1000
 )
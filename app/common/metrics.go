@@ -16,6 +16,26 @@ var (
 		Help: "The total number of RPC reconnections per chain",
 	}, []string{"chainName"})
 
+	PromRPCLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "lognite_rpc_latency_seconds",
+		Help: "The latency of RPC head probes per chain and endpoint",
+	}, []string{"chainName", "endpoint"})
+
+	PromRPCErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_rpc_errors",
+		Help: "The total number of RPC failures per chain and endpoint",
+	}, []string{"chainName", "endpoint"})
+
+	PromRPCHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lognite_rpc_head",
+		Help: "The latest head block number reported per chain and endpoint",
+	}, []string{"chainName", "endpoint"})
+
+	PromRPCActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lognite_rpc_active",
+		Help: "Whether the endpoint is currently used for ingestion per chain",
+	}, []string{"chainName", "endpoint"})
+
 	PromLogsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "lognite_logs_received",
 		Help: "The total number of received logs per chain and contract",
@@ -26,16 +46,97 @@ var (
 		Help: "The total number of events per chain, contract and event name",
 	}, []string{"chainName", "contractName", "eventName"})
 
+	PromCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_calls",
+		Help: "The total number of decoded calls per chain, contract and method name",
+	}, []string{"chainName", "contractName", "methodName"})
+
+	PromCallsRetracted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_calls_retracted",
+		Help: "The total number of calls retracted due to reorgs per chain and contract name",
+	}, []string{"chainName", "contractName"})
+
+	PromCallsMalformed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_calls_malformed",
+		Help: "The total number of calls that could not be decoded per chain and contract name",
+	}, []string{"chainName", "contractName"})
+
+	PromHeadBlock = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lognite_head_block",
+		Help: "The latest head block number per chain",
+	}, []string{"chainName"})
+
+	PromBlockNumber = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lognite_block_number",
+		Help: "The latest emitted block record number per chain",
+	}, []string{"chainName"})
+
+	PromBlocksRetracted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_blocks_retracted",
+		Help: "The total number of block records retracted due to reorgs per chain",
+	}, []string{"chainName"})
+
+	PromBaseFee = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lognite_base_fee",
+		Help: "The base fee of the latest emitted block record per chain",
+	}, []string{"chainName"})
+
+	PromState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lognite_state",
+		Help: "The latest numeric values of polled view functions per contract address, method, arguments and output",
+	}, []string{"chainName", "contractName", "address", "methodName", "args", "output"})
+
+	PromStateErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_state_errors",
+		Help: "The total number of failed view function calls per chain, contract and method name",
+	}, []string{"chainName", "contractName", "methodName"})
+
+	PromPendingCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_pending_calls",
+		Help: "The total number of decoded calls of pending transactions per chain, contract and method name",
+	}, []string{"chainName", "contractName", "methodName"})
+
+	PromInclusionLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lognite_inclusion_latency_seconds",
+		Help:    "The time from seeing a transaction in the mempool to its block per chain",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"chainName"})
+
+	PromEventsFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_events_filtered",
+		Help: "The total number of decoded events rejected by filter expressions",
+	}, []string{"chainName", "contractName", "eventName"})
+
 	PromEventsMalformed = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "lognite_events_malformed",
 		Help: "The total number of malformed events per chain and contract name",
 	}, []string{"chainName", "contractName"})
 
+	PromRawLogs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_raw_logs",
+		Help: "The total number of undecodable logs written raw per chain and contract name",
+	}, []string{"chainName", "contractName"})
+
 	PromReorgErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "lognite_reorg_errors",
 		Help: "The total number of errors due to chain reorgs that may affect data consistency",
 	}, []string{"chainName"})
 
+	PromReorgs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_reorgs",
+		Help: "The total number of detected chain reorgs per chain",
+	}, []string{"chainName"})
+
+	PromEventsRetracted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_events_retracted",
+		Help: "The total number of events retracted due to reorgs per chain and contract name",
+	}, []string{"chainName", "contractName"})
+
+	PromEventsConfirmed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_events_confirmed",
+		Help: "The total number of pending events confirmed per chain and contract name",
+	}, []string{"chainName", "contractName"})
+
 	PromConfiguredEvents = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "lognite_configured_events",
 		Help: "The total number of events configured per chain and contract",
@@ -46,6 +147,11 @@ var (
 		Help: "The total number of addresses configured per chain and contract",
 	}, []string{"chainName", "contractName"})
 
+	PromAddressesRetracted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_addresses_retracted",
+		Help: "The total number of discovered addresses retracted due to reorgs per chain and contract",
+	}, []string{"chainName", "contractName"})
+
 	PromPostgresErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "lognite_postgres_errors",
 		Help: "The total number of Postgres errors per table",
@@ -56,6 +162,11 @@ var (
 		Help: "The total number of Postgres inserts per table",
 	}, []string{"table"})
 
+	PromPostgresUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_postgres_updates",
+		Help: "The total number of Postgres event status updates per table",
+	}, []string{"table"})
+
 	PromPostgresDrops = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "lognite_postgres_drops",
 		Help: "The total number of Postgres drops per table",
@@ -65,4 +176,9 @@ var (
 		Name: "lognite_queue_discarded",
 		Help: "The total number of discarded items per queue",
 	}, []string{"queue"})
+
+	PromQueueFull = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lognite_queue_full",
+		Help: "The total number of times a producer had to wait for a full queue",
+	}, []string{"queue"})
 )
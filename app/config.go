@@ -18,36 +18,130 @@ type ConsoleConfig struct {
 }
 
 type PostgresConfig struct {
-	URL       string        `yaml:"url"`
-	Retention time.Duration `yaml:"retention"`
+	URL string `yaml:"url"`
+	// Retention is how long rows are kept by their block timestamp, zero keeps them forever.
+	// When unset it defaults to DefaultPostgresRetention, unless any contract has a 'start_block',
+	// in which case the backfilled history is kept forever.
+	Retention *time.Duration `yaml:"retention"`
+}
+
+type CheckpointsConfig struct {
+	File string `yaml:"file"`
 }
 
 type ServerConfig struct {
 	Port uint16 `yaml:"port"`
 }
 
+type FactoryConfig struct {
+	Contract string `yaml:"contract"`
+	Event    string `yaml:"event"`
+	Arg      string `yaml:"arg"`
+}
+
+type EventConfig struct {
+	Name   string              `yaml:"name"`
+	Topics map[string][]string `yaml:"topics"`
+	// Where is a predicate over decoded arguments, e.g. `value > 1e24 && from != 0x0`.
+	Where string `yaml:"where"`
+	// Anonymous events have no signature topic, their logs are matched by the number of topics and data length.
+	Anonymous bool `yaml:"anonymous"`
+}
+
+// UnmarshalYAML accepts either a plain event name or a mapping with event options.
+func (e *EventConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Name = value.Value
+		return nil
+	}
+	type eventConfig EventConfig
+	return value.Decode((*eventConfig)(e))
+}
+
+// StateConfig is a view function called every N blocks at the confirmed block.
+type StateConfig struct {
+	Method string   `yaml:"method"`
+	Args   []string `yaml:"args"`
+	Every  uint64   `yaml:"every"`
+}
+
 type ContractConfig struct {
-	ABI       string              `yaml:"abi"`
-	Address   ethcommon.Address   `yaml:"address"`
-	Addresses []ethcommon.Address `yaml:"addresses"`
-	Events    []string            `yaml:"events"`
+	ABI        string              `yaml:"abi"`
+	Address    ethcommon.Address   `yaml:"address"`
+	Addresses  []ethcommon.Address `yaml:"addresses"`
+	Events     []EventConfig       `yaml:"events"`
+	StartBlock uint64              `yaml:"start_block"`
+	Factory    *FactoryConfig      `yaml:"factory"`
+	Wildcard   bool                `yaml:"wildcard"`
+	Enrich     bool                `yaml:"enrich"`
+	Calls      bool                `yaml:"calls"`
+	CallTypes  []string            `yaml:"call_types"`
+	State      []StateConfig       `yaml:"state"`
+	RawLogs    bool                `yaml:"raw_logs"`
 }
 
+const (
+	FinalityDepth     = "depth"
+	FinalitySafe      = "safe"
+	FinalityFinalized = "finalized"
+)
+
+const (
+	TracerDebug  = "debug"
+	TracerParity = "parity"
+)
+
+// Call types of traced frames, CREATE includes CREATE2. Only calls and contract creations
+// are decoded by default: static and delegate calls would repeat every call of a proxy
+// and flood the calls with read-only frames.
+const (
+	CallTypeCall         = "CALL"
+	CallTypeCreate       = "CREATE"
+	CallTypeStaticCall   = "STATICCALL"
+	CallTypeDelegateCall = "DELEGATECALL"
+	CallTypeCallCode     = "CALLCODE"
+)
+
+// Integers are output as decimal strings, hex strings, or as JSON numbers when
+// they fit in 53 bits (decimal strings otherwise). The raw default keeps the
+// JSON numbers of any size, which consumers with float64 numbers round.
+const (
+	NumbersRaw     = "raw"
+	NumbersDecimal = "decimal"
+	NumbersHex     = "hex"
+	NumbersNumber  = "number"
+)
+
 type ChainConfig struct {
 	RPC           string                    `yaml:"rpc"`
+	RPCs          []string                  `yaml:"rpcs"`
+	ChainID       uint64                    `yaml:"chain_id"`
 	Confirmations uint                      `yaml:"confirmations"`
+	Finality      string                    `yaml:"finality"`
+	Optimistic    bool                      `yaml:"optimistic"`
+	MaxBlockRange uint64                    `yaml:"max_block_range"`
+	PollInterval  time.Duration             `yaml:"poll_interval"`
+	Tracer        string                    `yaml:"tracer"`
+	Blocks        bool                      `yaml:"blocks"`
+	Mempool       bool                      `yaml:"mempool"`
 	Contracts     map[string]ContractConfig `yaml:"contracts"`
 }
 
+type DecodingConfig struct {
+	Numbers string `yaml:"numbers"`
+}
+
 type OutputsConfig struct {
 	Console  *ConsoleConfig  `yaml:"console"`
 	Postgres *PostgresConfig `yaml:"postgres"`
 }
 
 type Config struct {
-	Chains  map[string]ChainConfig `yaml:"chains"`
-	Server  ServerConfig           `yaml:"server"`
-	Outputs OutputsConfig          `yaml:"outputs"`
+	Chains      map[string]ChainConfig `yaml:"chains"`
+	Server      ServerConfig           `yaml:"server"`
+	Outputs     OutputsConfig          `yaml:"outputs"`
+	Checkpoints CheckpointsConfig      `yaml:"checkpoints"`
+	Decoding    DecodingConfig         `yaml:"decoding"`
 }
 
 func LoadConfig(filepath string) (*Config, error) {
@@ -77,18 +171,67 @@ func adjustDefaultValues(config *Config) {
 		config.Server.Port = common.DefaultServerPort
 	}
 
-	if config.Outputs.Postgres != nil && config.Outputs.Postgres.Retention.Nanoseconds() == 0 {
-		config.Outputs.Postgres.Retention = common.DefaultPostgresRetention
+	if len(config.Checkpoints.File) == 0 {
+		config.Checkpoints.File = common.DefaultCheckpointsFile
+	}
+
+	if config.Outputs.Postgres != nil && config.Outputs.Postgres.Retention == nil {
+		retention := common.DefaultPostgresRetention
+		if hasStartBlock(config) {
+			retention = 0
+		}
+		config.Outputs.Postgres.Retention = &retention
+	}
+
+	if len(config.Decoding.Numbers) == 0 {
+		config.Decoding.Numbers = NumbersRaw
 	}
 
 	for chainName, chain := range config.Chains {
+		if len(chain.RPC) > 0 && len(chain.RPCs) == 0 {
+			chain.RPCs = []string{chain.RPC}
+		}
 		if chain.Confirmations == 0 {
 			chain.Confirmations = common.DefaultConfirmations
-			config.Chains[chainName] = chain
 		}
+		if len(chain.Finality) == 0 {
+			chain.Finality = FinalityDepth
+		}
+		if chain.MaxBlockRange == 0 {
+			chain.MaxBlockRange = common.DefaultMaxBlockRange
+		}
+		if chain.PollInterval == 0 {
+			chain.PollInterval = common.DefaultPollInterval
+		}
+		for contractName, contract := range chain.Contracts {
+			for i := range contract.State {
+				if contract.State[i].Every == 0 {
+					contract.State[i].Every = common.DefaultStatePollBlocks
+				}
+			}
+			if contract.Calls && len(contract.CallTypes) == 0 {
+				contract.CallTypes = []string{CallTypeCall, CallTypeCreate}
+			}
+			for i := range contract.CallTypes {
+				contract.CallTypes[i] = strings.ToUpper(contract.CallTypes[i])
+			}
+			chain.Contracts[contractName] = contract
+		}
+		config.Chains[chainName] = chain
 	}
 }
 
+func hasStartBlock(config *Config) bool {
+	for _, chain := range config.Chains {
+		for _, contract := range chain.Contracts {
+			if contract.StartBlock > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func validateConfig(config *Config) error {
 	zeroAddress := ethcommon.HexToAddress("0x00")
 	validIdentifier := regexp.MustCompile(`^[a-zA-Z]+(\_[a-zA-Z0-9]+)*$`)
@@ -101,12 +244,34 @@ func validateConfig(config *Config) error {
 		return errors.New("configuration has no chains")
 	}
 
+	numbers := config.Decoding.Numbers
+	if numbers != NumbersRaw && numbers != NumbersDecimal && numbers != NumbersHex && numbers != NumbersNumber {
+		return errors.New("'decoding.numbers' must be one of: raw, decimal, hex, number")
+	}
+
 	for chainName, chain := range config.Chains {
 		if !validIdentifier.MatchString(chainName) {
 			return fmt.Errorf("chain name '%s' is not a valid identifier", chainName)
 		}
-		if !strings.HasPrefix(chain.RPC, "wss://") {
-			return fmt.Errorf("chain '%s' 'rpc' has not wss scheme: %s", chainName, chain.RPC)
+		if len(chain.RPCs) == 0 {
+			return fmt.Errorf("chain '%s' has neither 'rpc' nor 'rpcs' specified", chainName)
+		}
+		if len(chain.RPC) > 0 && (len(chain.RPCs) != 1 || chain.RPCs[0] != chain.RPC) {
+			return fmt.Errorf("chain '%s' has both 'rpc' and 'rpcs' specified", chainName)
+		}
+		for _, rpc := range chain.RPCs {
+			if !strings.HasPrefix(rpc, "wss://") && !isHTTPURL(rpc) {
+				return fmt.Errorf("chain '%s' 'rpc' has neither wss nor http(s) scheme: %s", chainName, rpc)
+			}
+		}
+		if chain.Finality != FinalityDepth && chain.Finality != FinalitySafe && chain.Finality != FinalityFinalized {
+			return fmt.Errorf("chain '%s' 'finality' must be one of: depth, safe, finalized", chainName)
+		}
+		if chain.Tracer != "" && chain.Tracer != TracerDebug && chain.Tracer != TracerParity {
+			return fmt.Errorf("chain '%s' 'tracer' must be one of: debug, parity", chainName)
+		}
+		if chain.PollInterval < 100*time.Millisecond {
+			return fmt.Errorf("chain '%s' 'poll_interval' must be at least 100ms", chainName)
 		}
 		if len(chain.Contracts) == 0 {
 			return fmt.Errorf("chain '%s' has no contracts configured", chainName)
@@ -125,12 +290,46 @@ func validateConfig(config *Config) error {
 			if contract.Address != zeroAddress && len(contract.Addresses) != 0 {
 				return fmt.Errorf("chain '%s' contract '%s' has both 'address' and 'addresses' specified", chainName, contractName)
 			}
-			if contract.Address == zeroAddress && len(contract.Addresses) == 0 {
-				return fmt.Errorf("chain '%s' contract '%s' has neither 'address' nor 'addresses' specified", chainName, contractName)
+			hasAddresses := contract.Address != zeroAddress || len(contract.Addresses) != 0 || contract.Factory != nil
+			if contract.Wildcard && hasAddresses {
+				return fmt.Errorf("chain '%s' contract '%s' 'wildcard' cannot be combined with 'address', 'addresses' or 'factory'", chainName, contractName)
+			}
+			if contract.Wildcard && len(contract.Events) == 0 {
+				return fmt.Errorf("chain '%s' contract '%s' 'wildcard' requires 'events' specified", chainName, contractName)
+			}
+			if !contract.Wildcard && !hasAddresses {
+				return fmt.Errorf("chain '%s' contract '%s' has neither 'address', 'addresses', 'factory' nor 'wildcard' specified", chainName, contractName)
+			}
+			if contract.Factory != nil {
+				if _, exists := chain.Contracts[contract.Factory.Contract]; !exists || contract.Factory.Contract == contractName {
+					return fmt.Errorf("chain '%s' contract '%s' 'factory.contract' must be another contract of the chain", chainName, contractName)
+				}
+				if !validIdentifier.MatchString(contract.Factory.Event) || len(contract.Factory.Arg) == 0 {
+					return fmt.Errorf("chain '%s' contract '%s' 'factory' must have valid 'event' and 'arg'", chainName, contractName)
+				}
+			}
+			if contract.Calls && !hasAddresses {
+				return fmt.Errorf("chain '%s' contract '%s' 'calls' require contract addresses", chainName, contractName)
+			}
+			if len(contract.CallTypes) > 0 && !contract.Calls {
+				return fmt.Errorf("chain '%s' contract '%s' 'call_types' require 'calls' enabled", chainName, contractName)
+			}
+			for _, callType := range contract.CallTypes {
+				switch callType {
+				case CallTypeCall, CallTypeCreate, CallTypeStaticCall, CallTypeDelegateCall, CallTypeCallCode:
+				default:
+					return fmt.Errorf("chain '%s' contract '%s' 'call_types' must be some of: call, create, staticcall, delegatecall, callcode", chainName, contractName)
+				}
+			}
+			if len(contract.State) > 0 && !hasAddresses {
+				return fmt.Errorf("chain '%s' contract '%s' 'state' requires contract addresses", chainName, contractName)
+			}
+			if contract.RawLogs && !hasAddresses {
+				return fmt.Errorf("chain '%s' contract '%s' 'raw_logs' require contract addresses", chainName, contractName)
 			}
-			for _, eventName := range contract.Events {
-				if !validIdentifier.MatchString(eventName) {
-					return fmt.Errorf("chain '%s' contract '%s' has invalid 'events' value: '%s'", chainName, contractName, eventName)
+			for _, event := range contract.Events {
+				if !validIdentifier.MatchString(event.Name) {
+					return fmt.Errorf("chain '%s' contract '%s' has invalid 'events' value: '%s'", chainName, contractName, event.Name)
 				}
 			}
 		}
@@ -140,8 +339,8 @@ func validateConfig(config *Config) error {
 		if len(config.Outputs.Postgres.URL) == 0 {
 			return errors.New("'outputs.postgres' has no 'url' specified")
 		}
-		if config.Outputs.Postgres.Retention < time.Hour {
-			return errors.New("'outputs.postgres.retention' must be longer than 1h")
+		if retention := *config.Outputs.Postgres.Retention; retention != 0 && retention < time.Hour {
+			return errors.New("'outputs.postgres.retention' must be zero (disabled) or longer than 1h")
 		}
 	}
 
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseConfig(t *testing.T, source string) (*Config, error) {
+	t.Helper()
+	config := &Config{}
+	if err := yaml.Unmarshal([]byte(source), config); err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	adjustDefaultValues(config)
+	return config, validateConfig(config)
+}
+
+func TestValidateContractAddresses(t *testing.T) {
+	tests := []struct {
+		contract string
+		err      string
+	}{
+		{"address: '0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed'\n events: [Transfer]", ""},
+		{"wildcard: true\n events: [Transfer]", ""},
+		{"events: [Transfer]", "nor 'wildcard' specified"},
+		{"wildcard: false\n events: [Transfer]", "nor 'wildcard' specified"},
+		{"wildcard: true", "'wildcard' requires 'events'"},
+		{"wildcard: true\n address: '0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed'\n events: [Transfer]", "cannot be combined"},
+		{"wildcard: true\n events: [Transfer]\n raw_logs: true", "'raw_logs' require contract addresses"},
+	}
+	for _, tt := range tests {
+		source := `
+chains:
+  ethereum:
+    rpc: https://rpc.example
+    contracts:
+      Token:
+        abi: ERC20.abi
+        ` + strings.ReplaceAll(tt.contract, "\n ", "\n        ")
+		_, err := parseConfig(t, source)
+		if tt.err == "" && err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.contract, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%q: got error %v, want %q", tt.contract, err, tt.err)
+		}
+	}
+}
+
+func TestDecodingNumbersDefault(t *testing.T) {
+	config, err := parseConfig(t, `
+chains:
+  ethereum:
+    rpc: https://rpc.example
+    contracts:
+      Token:
+        abi: ERC20.abi
+        address: '0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed'
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Decoding.Numbers != NumbersRaw {
+		t.Errorf("default 'decoding.numbers' = %q, want %q", config.Decoding.Numbers, NumbersRaw)
+	}
+}
+
+func TestCallTypesDefault(t *testing.T) {
+	tests := []struct {
+		contract string
+		want     []string
+		err      string
+	}{
+		{"calls: true", []string{CallTypeCall, CallTypeCreate}, ""},
+		{"calls: true\n call_types: [call, staticcall]", []string{CallTypeCall, CallTypeStaticCall}, ""},
+		{"calls: true\n call_types: [selfdestruct]", nil, "'call_types' must be some of"},
+		{"call_types: [delegatecall]", nil, "'call_types' require 'calls' enabled"},
+		{"events: [Transfer]", nil, ""},
+	}
+	for _, tt := range tests {
+		source := `
+chains:
+  ethereum:
+    rpc: https://rpc.example
+    contracts:
+      Token:
+        abi: ERC20.abi
+        address: '0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed'
+        ` + strings.ReplaceAll(tt.contract, "\n ", "\n        ")
+		config, err := parseConfig(t, source)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: got error %v, want %q", tt.contract, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.contract, err)
+			continue
+		}
+		got := config.Chains["ethereum"].Contracts["Token"].CallTypes
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: got call types %v, want %v", tt.contract, got, tt.want)
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"math/big"
 	"os"
 	"path"
+	"reflect"
+	"strconv"
 	"strings"
 
 	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/expr"
 	"github.com/pinebit/lognite/app/types"
 )
 
@@ -37,11 +44,82 @@ func LoadContracts(config *Config, basePath string) (types.ContractsPerChain, er
 			common.PromConfiguredAddresses.WithLabelValues(chainName, contractName).Add(float64(len(addresses)))
 
 			allowedEvents := make(map[string]struct{})
-			for _, eventName := range contractConfig.Events {
-				allowedEvents[eventName] = struct{}{}
+			topicFilters := make(map[string][][]ethcommon.Hash)
+			predicates := make(map[string]types.Predicate)
+			var anonymousEvents []string
+			for _, eventConfig := range contractConfig.Events {
+				allowedEvents[eventConfig.Name] = struct{}{}
+				if eventConfig.Anonymous {
+					if event, exists := abi.Events[eventConfig.Name]; !exists || !event.Anonymous {
+						return nil, fmt.Errorf("chain '%s' contract '%s' event '%s' is not an anonymous event of the ABI", chainName, contractName, eventConfig.Name)
+					}
+					anonymousEvents = append(anonymousEvents, eventConfig.Name)
+				}
+				if eventConfig.Where != "" {
+					predicate, err := makePredicate(abi, eventConfig)
+					if err != nil {
+						return nil, fmt.Errorf("chain '%s' contract '%s' event '%s' has invalid 'where': %v", chainName, contractName, eventConfig.Name, err)
+					}
+					predicates[eventConfig.Name] = predicate
+				}
+				if len(eventConfig.Topics) == 0 {
+					continue
+				}
+				topics, err := makeTopicFilters(abi, eventConfig)
+				if err != nil {
+					return nil, fmt.Errorf("chain '%s' contract '%s' event '%s' has invalid 'topics': %v", chainName, contractName, eventConfig.Name, err)
+				}
+				topicFilters[eventConfig.Name] = topics
+			}
+
+			var factory *types.Factory
+			if contractConfig.Factory != nil {
+				factoryConfig := chainConfig.Contracts[contractConfig.Factory.Contract]
+				factoryABI, err := readABI(path.Join(basePath, factoryConfig.ABI))
+				if err != nil {
+					return nil, err
+				}
+				if err := validateFactoryEvent(factoryABI, contractConfig.Factory); err != nil {
+					return nil, fmt.Errorf("chain '%s' contract '%s' has invalid 'factory': %v", chainName, contractName, err)
+				}
+				factory = &types.Factory{
+					ContractName: contractConfig.Factory.Contract,
+					EventName:    contractConfig.Factory.Event,
+					ArgName:      contractConfig.Factory.Arg,
+				}
+			}
+
+			// Anonymous events are matched among all logs of the addresses, which contracts with own queries do not pull.
+			if len(anonymousEvents) > 0 && (len(topicFilters) > 0 || len(addresses) == 0 && factory == nil) {
+				return nil, fmt.Errorf("chain '%s' contract '%s' anonymous events require contract addresses and no 'topics' filters", chainName, contractName)
+			}
+
+			stateQueries, err := makeStateQueries(abi, contractConfig.State)
+			if err != nil {
+				return nil, fmt.Errorf("chain '%s' contract '%s' has invalid 'state': %v", chainName, contractName, err)
+			}
+
+			callTypes := make(map[string]struct{})
+			for _, callType := range contractConfig.CallTypes {
+				callTypes[callType] = struct{}{}
 			}
 
-			newContract := types.NewContract(chainName, contractName, abi, addresses, allowedEvents)
+			options := types.ContractOptions{
+				AllowedEvents:   allowedEvents,
+				StartBlock:      contractConfig.StartBlock,
+				Factory:         factory,
+				TopicFilters:    topicFilters,
+				Wildcard:        contractConfig.Wildcard,
+				Predicates:      predicates,
+				Enrich:          contractConfig.Enrich,
+				Calls:           contractConfig.Calls,
+				CallTypes:       callTypes,
+				StateQueries:    stateQueries,
+				NumberEncoding:  config.Decoding.Numbers,
+				RawLogs:         contractConfig.RawLogs,
+				AnonymousEvents: anonymousEvents,
+			}
+			newContract := types.NewContract(chainName, contractName, abi, addresses, options)
 			contracts[chainName] = append(contracts[chainName], newContract)
 		}
 	}
@@ -49,6 +127,216 @@ func LoadContracts(config *Config, basePath string) (types.ContractsPerChain, er
 	return contracts, nil
 }
 
+// RestoreDiscoveredAddresses adds the persisted addresses of factory-discovered contracts.
+func RestoreDiscoveredAddresses(ctx context.Context, checkpoints types.Checkpoints, contracts []types.Contract) error {
+	for _, contract := range contracts {
+		if contract.Factory() == nil {
+			continue
+		}
+		addresses, err := checkpoints.LoadAddresses(ctx, contract.ChainName(), contract.Name())
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			contract.AddAddress(address)
+		}
+		common.PromConfiguredAddresses.WithLabelValues(contract.ChainName(), contract.Name()).Add(float64(len(addresses)))
+	}
+	return nil
+}
+
+// makeTopicFilters converts configured values of indexed event arguments into topics
+// of eth_getLogs filter, the event signature topic is not included.
+func makeTopicFilters(abi *ethabi.ABI, eventConfig EventConfig) ([][]ethcommon.Hash, error) {
+	event, exists := abi.Events[eventConfig.Name]
+	if !exists {
+		return nil, fmt.Errorf("event is not found in the ABI")
+	}
+
+	matched := 0
+	var query [][]interface{}
+	for _, input := range event.Inputs {
+		if !input.Indexed {
+			if _, exists := eventConfig.Topics[input.Name]; exists {
+				return nil, fmt.Errorf("argument '%s' is not indexed", input.Name)
+			}
+			continue
+		}
+		var rules []interface{}
+		for _, value := range eventConfig.Topics[input.Name] {
+			rule, err := parseTopicValue(input.Type, value)
+			if err != nil {
+				return nil, fmt.Errorf("argument '%s' value '%s': %v", input.Name, value, err)
+			}
+			rules = append(rules, rule)
+		}
+		if len(rules) > 0 {
+			matched++
+		}
+		query = append(query, rules)
+	}
+	if matched != len(eventConfig.Topics) {
+		return nil, fmt.Errorf("unknown argument or no values specified")
+	}
+
+	topics, err := ethabi.MakeTopics(query...)
+	if err != nil {
+		return nil, err
+	}
+	// Trailing wildcard positions are redundant in the filter.
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+	return topics, nil
+}
+
+// makePredicate compiles the event 'where' expression, all identifiers must be event arguments.
+func makePredicate(abi *ethabi.ABI, eventConfig EventConfig) (*expr.Expr, error) {
+	event, exists := abi.Events[eventConfig.Name]
+	if !exists {
+		return nil, fmt.Errorf("event is not found in the ABI")
+	}
+
+	predicate, err := expr.Parse(eventConfig.Where)
+	if err != nil {
+		return nil, err
+	}
+	for _, ident := range predicate.Identifiers() {
+		found := false
+		for _, input := range event.Inputs {
+			if input.Name == ident {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("event has no argument '%s'", ident)
+		}
+	}
+	return predicate, nil
+}
+
+// makeStateQueries packs calls of the configured view functions.
+func makeStateQueries(abi *ethabi.ABI, stateConfigs []StateConfig) ([]types.StateQuery, error) {
+	var queries []types.StateQuery
+	for _, stateConfig := range stateConfigs {
+		method, exists := abi.Methods[stateConfig.Method]
+		if !exists {
+			return nil, fmt.Errorf("method '%s' is not found in the ABI", stateConfig.Method)
+		}
+		if len(method.Inputs) != len(stateConfig.Args) {
+			return nil, fmt.Errorf("method '%s' expects %d arguments, got %d", method.Name, len(method.Inputs), len(stateConfig.Args))
+		}
+		var args []interface{}
+		for i, input := range method.Inputs {
+			arg, err := parseCallArgument(input.Type, stateConfig.Args[i])
+			if err != nil {
+				return nil, fmt.Errorf("method '%s' argument %d value '%s': %v", method.Name, i, stateConfig.Args[i], err)
+			}
+			args = append(args, arg)
+		}
+		data, err := abi.Pack(method.Name, args...)
+		if err != nil {
+			return nil, fmt.Errorf("method '%s': %v", method.Name, err)
+		}
+		queries = append(queries, types.StateQuery{
+			Method: method.Name,
+			Args:   stateConfig.Args,
+			Data:   data,
+			Every:  stateConfig.Every,
+		})
+	}
+	return queries, nil
+}
+
+// parseCallArgument converts a configured value into the Go type the ABI packs for the argument type.
+func parseCallArgument(t ethabi.Type, value string) (interface{}, error) {
+	switch t.T {
+	case ethabi.IntTy, ethabi.UintTy:
+		number, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, fmt.Errorf("not an integer")
+		}
+		if t.Size > 64 {
+			return number, nil
+		}
+		result := reflect.New(t.GetType()).Elem()
+		if t.T == ethabi.UintTy {
+			if !number.IsUint64() || result.OverflowUint(number.Uint64()) {
+				return nil, fmt.Errorf("out of range")
+			}
+			result.SetUint(number.Uint64())
+		} else {
+			if !number.IsInt64() || result.OverflowInt(number.Int64()) {
+				return nil, fmt.Errorf("out of range")
+			}
+			result.SetInt(number.Int64())
+		}
+		return result.Interface(), nil
+	case ethabi.FixedBytesTy:
+		data, err := hexutil.Decode(value)
+		if err != nil || len(data) != t.Size {
+			return nil, fmt.Errorf("not a bytes%d hex value", t.Size)
+		}
+		result := reflect.New(t.GetType()).Elem()
+		reflect.Copy(result, reflect.ValueOf(data))
+		return result.Interface(), nil
+	case ethabi.AddressTy, ethabi.BoolTy, ethabi.StringTy, ethabi.BytesTy:
+		return parseTopicValue(t, value)
+	default:
+		return nil, fmt.Errorf("%s arguments are not supported", t.String())
+	}
+}
+
+func parseTopicValue(t ethabi.Type, value string) (interface{}, error) {
+	switch t.T {
+	case ethabi.AddressTy:
+		if !ethcommon.IsHexAddress(value) {
+			return nil, fmt.Errorf("not an address")
+		}
+		return ethcommon.HexToAddress(value), nil
+	case ethabi.IntTy, ethabi.UintTy:
+		number, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, fmt.Errorf("not an integer")
+		}
+		// Negative values are encoded in two's complement.
+		return math.U256(number), nil
+	case ethabi.BoolTy:
+		return strconv.ParseBool(value)
+	case ethabi.FixedBytesTy:
+		data, err := hexutil.Decode(value)
+		if err != nil || len(data) != t.Size {
+			return nil, fmt.Errorf("not a bytes%d hex value", t.Size)
+		}
+		var hash ethcommon.Hash
+		copy(hash[:], data)
+		return hash, nil
+	case ethabi.StringTy:
+		return value, nil
+	case ethabi.BytesTy:
+		return hexutil.Decode(value)
+	default:
+		return nil, fmt.Errorf("filtering by %s is not supported", t.String())
+	}
+}
+
+func validateFactoryEvent(abi *ethabi.ABI, factory *FactoryConfig) error {
+	event, exists := abi.Events[factory.Event]
+	if !exists {
+		return fmt.Errorf("event '%s' is not found in the factory ABI", factory.Event)
+	}
+	for _, input := range event.Inputs {
+		if input.Name == factory.Arg {
+			if input.Type.T != ethabi.AddressTy {
+				return fmt.Errorf("event '%s' argument '%s' is not an address", factory.Event, factory.Arg)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("event '%s' has no argument '%s'", factory.Event, factory.Arg)
+}
+
 func readABI(filepath string) (*ethabi.ABI, error) {
 	abiData, err := os.ReadFile(filepath)
 	if err != nil {
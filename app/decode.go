@@ -1,9 +1,15 @@
 package app
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
 	"time"
 
 	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/pinebit/lognite/app/types"
@@ -11,7 +17,7 @@ import (
 
 func decodeEvent(blockTs time.Time, log *ethtypes.Log, contract types.Contract) (*types.Event, error) {
 	abi := contract.ABI()
-	event, err := abi.EventByID(log.Topics[0])
+	event, err := matchEvent(log, contract)
 	if err != nil {
 		return nil, err
 	}
@@ -34,11 +40,147 @@ func decodeEvent(blockTs time.Time, log *ethtypes.Log, contract types.Contract)
 			TxHash:      log.TxHash,
 			TxIndex:     log.TxIndex,
 			LogIndex:    log.Index,
+			Status:      types.EventConfirmed,
+			Topics:      log.Topics,
+			Data:        log.Data,
 		}
 		return eventData, nil
 	}
 }
 
+// matchEvent finds the event of the log by its signature topic, or else among the anonymous
+// events of the contract by the number of topics and the data length, the first that decodes.
+func matchEvent(log *ethtypes.Log, contract types.Contract) (*ethabi.Event, error) {
+	abi := contract.ABI()
+	if len(log.Topics) > 0 {
+		if event, err := abi.EventByID(log.Topics[0]); err == nil {
+			return event, nil
+		}
+	}
+	for _, eventName := range contract.AnonymousEvents() {
+		event := abi.Events[eventName]
+		if !matchesAnonymousEvent(log, &event) {
+			continue
+		}
+		if _, err := event.Inputs.UnpackValues(log.Data); err == nil {
+			return &event, nil
+		}
+	}
+	if len(log.Topics) == 0 {
+		return nil, errors.New("log has no topics")
+	}
+	return nil, fmt.Errorf("no event with signature %s", log.Topics[0].Hex())
+}
+
+func matchesAnonymousEvent(log *ethtypes.Log, event *ethabi.Event) bool {
+	if len(log.Topics) != len(indexedArguments(event.Inputs)) || len(log.Data)%32 != 0 {
+		return false
+	}
+	size, dynamic := 0, false
+	for _, input := range event.Inputs.NonIndexed() {
+		inputSize, inputDynamic := encodedSize(input.Type)
+		size += inputSize
+		dynamic = dynamic || inputDynamic
+	}
+	if dynamic {
+		return len(log.Data) >= size
+	}
+	return len(log.Data) == size
+}
+
+// encodedSize returns the size of the type in the head of ABI encoded data, which is
+// the size of an offset for dynamic types, and whether the type is dynamic.
+func encodedSize(t ethabi.Type) (int, bool) {
+	switch t.T {
+	case ethabi.StringTy, ethabi.BytesTy, ethabi.SliceTy:
+		return 32, true
+	case ethabi.ArrayTy:
+		elemSize, dynamic := encodedSize(*t.Elem)
+		if dynamic {
+			return 32, true
+		}
+		return t.Size * elemSize, false
+	case ethabi.TupleTy:
+		size := 0
+		for _, elem := range t.TupleElems {
+			elemSize, dynamic := encodedSize(*elem)
+			if dynamic {
+				return 32, true
+			}
+			size += elemSize
+		}
+		return size, false
+	}
+	return 32, false
+}
+
+// decodeCall decodes the call input against the contract ABI. Calls without input
+// are reported as the receive method, and unknown selectors as the fallback method if the ABI has one.
+func decodeCall(blockTs time.Time, block *rpcBlock, frame *callFrame, contract types.Contract) (*types.Call, error) {
+	abi := contract.ABI()
+	call := &types.Call{
+		MethodArgs:   make(map[string]interface{}),
+		Contract:     contract,
+		Address:      frame.to,
+		From:         frame.from,
+		Value:        frame.value,
+		CallType:     frame.callType,
+		TraceAddress: frame.traceAddress,
+		BlockTs:      blockTs,
+		BlockNumber:  uint64(block.Number),
+		BlockHash:    block.Hash,
+		TxHash:       frame.txHash,
+		TxIndex:      frame.txIndex,
+		Success:      frame.success,
+		Status:       types.EventConfirmed,
+	}
+
+	// Constructor arguments cannot be told apart from the creation code of the contract.
+	if frame.callType == CallTypeCreate || frame.callType == "CREATE2" {
+		call.MethodName = "constructor"
+		return call, nil
+	}
+	if len(frame.input) == 0 {
+		call.MethodName = "receive"
+		return call, nil
+	}
+	if len(frame.input) < 4 {
+		return nil, fmt.Errorf("input is too short")
+	}
+	method, err := abi.MethodById(frame.input[:4])
+	if err != nil {
+		if abi.HasFallback() {
+			call.MethodName = "fallback"
+			return call, nil
+		}
+		return nil, err
+	}
+	if err := method.Inputs.UnpackIntoMap(call.MethodArgs, frame.input[4:]); err != nil {
+		return nil, err
+	}
+	normalizeValues(method.Inputs, call.MethodArgs)
+	call.MethodName = method.Name
+	return call, nil
+}
+
+// decodeStateValues unpacks outputs of a view function, unnamed outputs are named by their position.
+func decodeStateValues(contract types.Contract, methodName string, data []byte) (map[string]interface{}, error) {
+	method := contract.ABI().Methods[methodName]
+	unpacked, err := method.Outputs.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(unpacked))
+	for i, output := range method.Outputs {
+		name := output.Name
+		if name == "" {
+			name = fmt.Sprintf("output%d", i)
+		}
+		values[name] = normalizeValue(output.Type, unpacked[i])
+	}
+	return values, nil
+}
+
 func parseArgumentValues(log *ethtypes.Log, abi *ethabi.ABI, event *ethabi.Event) (map[string]interface{}, error) {
 	dataValues := make(map[string]interface{})
 	if err := abi.UnpackIntoMap(dataValues, event.Name, log.Data); err != nil {
@@ -47,7 +189,14 @@ func parseArgumentValues(log *ethtypes.Log, abi *ethabi.ABI, event *ethabi.Event
 
 	allValues := make(map[string]interface{})
 	indexedArgs := indexedArguments(event.Inputs)
-	if err := ethabi.ParseTopicsIntoMap(allValues, indexedArgs, log.Topics[1:]); err != nil {
+	topics := log.Topics
+	if !event.Anonymous {
+		if len(topics) == 0 {
+			return nil, errors.New("log has no topics")
+		}
+		topics = topics[1:]
+	}
+	if err := ethabi.ParseTopicsIntoMap(allValues, indexedArgs, topics); err != nil {
 		return nil, err
 	}
 
@@ -55,7 +204,7 @@ func parseArgumentValues(log *ethtypes.Log, abi *ethabi.ABI, event *ethabi.Event
 		allValues[k] = v
 	}
 
-	hexifyRawBytes(allValues)
+	normalizeValues(event.Inputs, allValues)
 
 	return allValues, nil
 }
@@ -70,13 +219,121 @@ func indexedArguments(args ethabi.Arguments) ethabi.Arguments {
 	return indexed
 }
 
-func hexifyRawBytes(kv map[string]interface{}) {
-	for k, v := range kv {
-		switch val := v.(type) {
-		case []byte:
-			kv[k] = hexutil.Encode(val)
-		case [32]byte:
-			kv[k] = hexutil.Encode(val[:])
+// normalizeValues converts decoded values of the arguments into canonical JSON-friendly
+// values, walking the ABI types: tuples become objects with the ABI field names, bytes become
+// hex strings and addresses become checksummed hex strings. Integers are left as is
+// for filters and gauges, encodeNumbers applies the number encoding before output.
+func normalizeValues(args ethabi.Arguments, values map[string]interface{}) {
+	for _, arg := range args {
+		if value, exists := values[arg.Name]; exists {
+			values[arg.Name] = normalizeValue(arg.Type, value)
+		}
+	}
+}
+
+func normalizeValue(t ethabi.Type, value interface{}) interface{} {
+	// Indexed arguments of dynamic types are only known by the hash of their value.
+	if hash, ok := value.(ethcommon.Hash); ok && t.T != ethabi.FixedBytesTy {
+		return hash.Hex()
+	}
+
+	switch t.T {
+	case ethabi.AddressTy:
+		if address, ok := value.(ethcommon.Address); ok {
+			return address.Hex()
+		}
+	case ethabi.BytesTy:
+		if data, ok := value.([]byte); ok {
+			return hexutil.Encode(data)
+		}
+	case ethabi.FixedBytesTy, ethabi.FunctionTy:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Array {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return hexutil.Encode(data)
+		}
+	case ethabi.SliceTy, ethabi.ArrayTy:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			items := make([]interface{}, rv.Len())
+			for i := range items {
+				items[i] = normalizeValue(*t.Elem, rv.Index(i).Interface())
+			}
+			return items
+		}
+	case ethabi.TupleTy:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
 		}
+		if rv.Kind() == reflect.Struct && rv.NumField() == len(t.TupleElems) {
+			fields := make(map[string]interface{}, len(t.TupleElems))
+			for i, elem := range t.TupleElems {
+				name := t.TupleRawNames[i]
+				if name == "" {
+					name = strconv.Itoa(i)
+				}
+				fields[name] = normalizeValue(*elem, rv.Field(i).Interface())
+			}
+			return fields
+		}
+	}
+	return value
+}
+
+// maxSafeInteger is the largest integer represented exactly by JSON numbers of most consumers (2^53-1).
+var maxSafeInteger = big.NewInt(1<<53 - 1)
+
+// encodeNumbers applies the number encoding to the integers of the normalized values in place,
+// including the ones nested in tuples and arrays. The raw encoding leaves them as is.
+func encodeNumbers(values map[string]interface{}, encoding string) {
+	if encoding == NumbersRaw {
+		return
+	}
+	for name, value := range values {
+		values[name] = encodeNumber(value, encoding)
+	}
+}
+
+func encodeNumber(value interface{}, encoding string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		encodeNumbers(v, encoding)
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = encodeNumber(v[i], encoding)
+		}
+		return v
+	case *big.Int:
+		if v == nil {
+			return nil
+		}
+		return formatNumber(v, encoding)
+	case bool, string:
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return formatNumber(big.NewInt(rv.Int()), encoding)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return formatNumber(new(big.Int).SetUint64(rv.Uint()), encoding)
+	}
+	return value
+}
+
+func formatNumber(n *big.Int, encoding string) interface{} {
+	switch encoding {
+	case NumbersDecimal:
+		return n.String()
+	case NumbersHex:
+		return hexutil.EncodeBig(n)
+	}
+	if n.CmpAbs(maxSafeInteger) <= 0 {
+		return n.Int64()
 	}
+	return n.String()
 }
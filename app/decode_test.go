@@ -0,0 +1,60 @@
+package app
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestEncodeNumbers(t *testing.T) {
+	large, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tests := []struct {
+		encoding string
+		want     string
+	}{
+		{NumbersRaw, `{"large":123456789012345678901234567890,"nested":{"small":42},"small":42,"items":[255,-1]}`},
+		{NumbersNumber, `{"large":"123456789012345678901234567890","nested":{"small":42},"small":42,"items":[255,-1]}`},
+		{NumbersDecimal, `{"large":"123456789012345678901234567890","nested":{"small":"42"},"small":"42","items":["255","-1"]}`},
+		{NumbersHex, `{"large":"0x18ee90ff6c373e0ee4e3f0ad2","nested":{"small":"0x2a"},"small":"0x2a","items":["0xff","-0x1"]}`},
+	}
+	for _, tt := range tests {
+		values := map[string]interface{}{
+			"large":  new(big.Int).Set(large),
+			"small":  big.NewInt(42),
+			"nested": map[string]interface{}{"small": uint64(42)},
+			"items":  []interface{}{uint8(255), int32(-1)},
+		}
+		encodeNumbers(values, tt.encoding)
+
+		var got, want interface{}
+		data, err := json.Marshal(values)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", tt.encoding, err)
+		}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: unmarshal: %v", tt.encoding, err)
+		}
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatalf("%s: unmarshal: %v", tt.encoding, err)
+		}
+		if gotJSON, wantJSON := mustMarshal(t, got), mustMarshal(t, want); gotJSON != wantJSON {
+			t.Errorf("%s: got %s, want %s", tt.encoding, gotJSON, wantJSON)
+		}
+	}
+
+	// The raw default must keep the output of integers byte for byte.
+	values := map[string]interface{}{"large": large}
+	encodeNumbers(values, NumbersRaw)
+	if got := mustMarshal(t, values); got != `{"large":123456789012345678901234567890}` {
+		t.Errorf("raw: got %s", got)
+	}
+}
+
+func mustMarshal(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pinebit/lognite/app/common"
+	"go.uber.org/zap"
+)
+
+type endpoint struct {
+	url      string
+	label    string
+	head     uint64
+	failures uint
+	failedAt time.Time
+	// disabledUntil excludes the endpoint regardless of its head, e.g. when it serves a wrong chain.
+	disabledUntil time.Time
+}
+
+// endpoints keeps health of the chain RPC endpoints ordered by priority.
+// An endpoint is healthy unless it failed recently, and it is lagging when
+// its head is behind the best known head by more than DefaultMaxEndpointLag blocks.
+type endpoints struct {
+	chainName string
+	mu        sync.Mutex
+	list      []*endpoint
+}
+
+func newEndpoints(chainName string, urls []string) *endpoints {
+	e := &endpoints{
+		chainName: chainName,
+	}
+	for _, rawURL := range urls {
+		// Only the host is used in metrics, as URLs often carry API keys.
+		label := rawURL
+		if u, err := url.Parse(rawURL); err == nil {
+			label = u.Host
+		}
+		e.list = append(e.list, &endpoint{
+			url:   rawURL,
+			label: label,
+		})
+	}
+	return e
+}
+
+// best returns the highest priority endpoint that is healthy and not lagging,
+// falling back to the endpoint that failed or was disabled the longest time ago.
+func (e *endpoints) best() *endpoint {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bestHead := e.bestHead()
+	for _, ep := range e.list {
+		if e.isHealthy(ep) && !e.isLagging(ep, bestHead) {
+			return ep
+		}
+	}
+	for _, ep := range e.list {
+		if e.isHealthy(ep) {
+			return ep
+		}
+	}
+	oldest := e.list[0]
+	for _, ep := range e.list[1:] {
+		if ep.failedAt.Before(oldest.failedAt) {
+			oldest = ep
+		}
+	}
+	return oldest
+}
+
+func (e *endpoints) reportHead(ep *endpoint, head uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ep.head = head
+	ep.failures = 0
+	common.PromRPCHead.WithLabelValues(e.chainName, ep.label).Set(float64(head))
+}
+
+func (e *endpoints) reportFailure(ep *endpoint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ep.failures++
+	ep.failedAt = time.Now()
+	common.PromRPCErrors.WithLabelValues(e.chainName, ep.label).Inc()
+}
+
+// disable excludes the endpoint for DefaultEndpointDisabledFor, e.g. when it serves a wrong chain.
+// It is verified again once picked after that.
+func (e *endpoints) disable(ep *endpoint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ep.failedAt = time.Now()
+	ep.disabledUntil = ep.failedAt.Add(common.DefaultEndpointDisabledFor)
+	common.PromRPCErrors.WithLabelValues(e.chainName, ep.label).Inc()
+}
+
+func (e *endpoints) setActive(active *endpoint) {
+	for _, ep := range e.list {
+		value := 0.0
+		if ep == active {
+			value = 1.0
+		}
+		common.PromRPCActive.WithLabelValues(e.chainName, ep.label).Set(value)
+	}
+}
+
+func (e *endpoints) bestHead() uint64 {
+	var bestHead uint64
+	for _, ep := range e.list {
+		if e.isHealthy(ep) && ep.head > bestHead {
+			bestHead = ep.head
+		}
+	}
+	return bestHead
+}
+
+func (e *endpoints) isHealthy(ep *endpoint) bool {
+	return time.Now().After(ep.disabledUntil) && (ep.failures == 0 || time.Since(ep.failedAt) > common.DefaultEndpointCooldown)
+}
+
+func (e *endpoints) isLagging(ep *endpoint, bestHead uint64) bool {
+	return ep.head+common.DefaultMaxEndpointLag < bestHead
+}
+
+// probe periodically polls heads of all endpoints, feeding the health state and metrics.
+// It runs for a single endpoint as well, which gets its latency and head reported.
+func (e *endpoints) probe(ctx context.Context, logger *zap.SugaredLogger) {
+	clients := make(map[*endpoint]*chainClient)
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(common.DefaultProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, ep := range e.list {
+			if err := e.probeEndpoint(ctx, ep, clients); err != nil && ctx.Err() == nil {
+				logger.Warnw("RPC endpoint probe failed", "endpoint", ep.label, "err", err)
+				e.reportFailure(ep)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *endpoints) probeEndpoint(ctx context.Context, ep *endpoint, clients map[*endpoint]*chainClient) error {
+	ctx, cancel := context.WithTimeout(ctx, common.DefaultProbeInterval)
+	defer cancel()
+
+	client, exists := clients[ep]
+	if !exists {
+		var err error
+		if client, err = dialChainClient(ctx, ep.url); err != nil {
+			return err
+		}
+		clients[ep] = client
+	}
+
+	start := time.Now()
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		client.Close()
+		delete(clients, ep)
+		return err
+	}
+	common.PromRPCLatency.WithLabelValues(e.chainName, ep.label).Observe(time.Since(start).Seconds())
+	e.reportHead(ep, head)
+	return nil
+}
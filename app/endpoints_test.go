@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pinebit/lognite/app/common"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestEndpointsRecover(t *testing.T) {
+	e := newEndpoints("test", []string{"https://primary.example", "https://secondary.example"})
+	primary, secondary := e.list[0], e.list[1]
+
+	if best := e.best(); best != primary {
+		t.Fatalf("best = %s, want the primary endpoint", best.label)
+	}
+
+	e.disable(primary)
+	if best := e.best(); best != secondary {
+		t.Fatalf("best = %s, want the secondary endpoint while the primary is disabled", best.label)
+	}
+
+	// With every endpoint out, the one that went out first is retried.
+	e.reportFailure(secondary)
+	if best := e.best(); best != primary {
+		t.Fatalf("best = %s, want the endpoint disabled first", best.label)
+	}
+
+	primary.disabledUntil = time.Now().Add(-time.Second)
+	secondary.failedAt = time.Now().Add(-common.DefaultEndpointCooldown - time.Second)
+	if !e.isHealthy(primary) || !e.isHealthy(secondary) {
+		t.Fatal("endpoints did not recover after the cooldown")
+	}
+	if best := e.best(); best != primary {
+		t.Fatalf("best = %s, want the recovered primary endpoint", best.label)
+	}
+}
+
+func TestProbeSingleEndpoint(t *testing.T) {
+	server := newFakeRPC(t, cannedResults(map[string]string{"eth_blockNumber": `"0x10"`}))
+	defer server.Close()
+
+	e := newEndpoints("probe-test", []string{server.URL})
+	ep := e.list[0]
+	clients := make(map[*endpoint]*chainClient)
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+
+	if err := e.probeEndpoint(context.Background(), ep, clients); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if ep.head != 16 {
+		t.Errorf("head = %d, want 16", ep.head)
+	}
+	if got := metricValue(t, common.PromRPCHead.WithLabelValues("probe-test", ep.label)).GetGauge().GetValue(); got != 16 {
+		t.Errorf("head metric = %v, want 16", got)
+	}
+	latency := common.PromRPCLatency.WithLabelValues("probe-test", ep.label).(prometheus.Metric)
+	if got := metricValue(t, latency).GetHistogram().GetSampleCount(); got != 1 {
+		t.Errorf("latency samples = %d, want 1", got)
+	}
+}
+
+func metricValue(t *testing.T, metric prometheus.Metric) *dto.Metric {
+	t.Helper()
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("metric: %v", err)
+	}
+	return &m
+}
@@ -0,0 +1,184 @@
+// Package expr implements predicate expressions over decoded event arguments, e.g.
+// `value > 1e24 && from != 0x0`. Supported are the ||, && and ! logical operators,
+// comparisons ==, !=, <, <=, >, >=, parentheses, integer literals (decimal, scientific
+// or hex), quoted strings, true and false. Addresses, hex bytes and integers compare numerically.
+package expr
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type Expr struct {
+	root   node
+	idents []string
+}
+
+// Parse compiles the expression source.
+func Parse(source string) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	return &Expr{
+		root:   root,
+		idents: p.idents,
+	}, nil
+}
+
+// Identifiers returns names of the arguments the expression refers to.
+func (e *Expr) Identifiers() []string {
+	return e.idents
+}
+
+// Match evaluates the expression against the arguments.
+func (e *Expr) Match(args map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(args)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression result is not a boolean")
+	}
+	return result, nil
+}
+
+type node interface {
+	eval(args map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(args map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n identNode) eval(args map[string]interface{}) (interface{}, error) {
+	value, exists := args[n.name]
+	if !exists {
+		return nil, fmt.Errorf("unknown argument '%s'", n.name)
+	}
+	return value, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(args map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operand of '!' is not a boolean")
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) eval(args map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, args, n.op)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit evaluation.
+	if (n.op == "&&" && !left) || (n.op == "||" && left) {
+		return left, nil
+	}
+	return evalBool(n.right, args, n.op)
+}
+
+func evalBool(n node, args map[string]interface{}, op string) (bool, error) {
+	value, err := n.eval(args)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("operand of '%s' is not a boolean", op)
+	}
+	return b, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(args map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if leftNumber, rightNumber, ok := asNumbers(left, right); ok {
+		return compareResult(n.op, leftNumber.Cmp(rightNumber))
+	}
+
+	switch l := left.(type) {
+	case bool:
+		if r, ok := right.(bool); ok && (n.op == "==" || n.op == "!=") {
+			return (l == r) == (n.op == "=="), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return compareResult(n.op, strings.Compare(l, r))
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %T and %T with '%s'", left, right, n.op)
+}
+
+func compareResult(op string, cmp int) (bool, error) {
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator '%s'", op)
+}
+
+// asNumbers converts both operands to integers when at least one of them is
+// numeric by nature (not a plain string), and the other one is convertible.
+func asNumbers(left, right interface{}) (*big.Int, *big.Int, bool) {
+	leftNumber, leftNumeric := toNumber(left)
+	rightNumber, rightNumeric := toNumber(right)
+	if leftNumber == nil || rightNumber == nil || (!leftNumeric && !rightNumeric) {
+		return nil, nil, false
+	}
+	return leftNumber, rightNumber, true
+}
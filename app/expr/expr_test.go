@@ -0,0 +1,155 @@
+package expr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMatch(t *testing.T) {
+	value, _ := new(big.Int).SetString("2000000000000000000000000", 10)
+	sender := common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	args := map[string]interface{}{
+		"value":    value,
+		"from":     sender,
+		"to":       common.Address{},
+		"sender":   sender.Hex(),
+		"amount":   uint64(1500),
+		"approved": true,
+		"symbol":   "USDC",
+		"data":     []byte{0x01, 0x02},
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"value > 1e24 && from != 0x0", true},
+		{"value > 1e25 && from != 0x0", false},
+		{"value > 1e24 && to != 0x0", false},
+		{"value == 2000000000000000000000000", true},
+		{"value >= 2e24 && value <= 2e24", true},
+		{"from == 0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"from == 0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", true},
+		{"sender == 0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"sender == from", true},
+		{"to == 0x0000000000000000000000000000000000000000", true},
+		{"amount > 1.5e3", false},
+		{"amount == 0x5dc", true},
+		{"data == 0x0102", true},
+		{"approved && !(amount < 1000)", true},
+		{"approved == false || symbol == 'USDC'", true},
+		{"symbol == \"DAI\"", false},
+		{"!approved", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.source)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.source, err)
+			continue
+		}
+		got, err := e.Match(args)
+		if err != nil {
+			t.Errorf("Match(%q) error: %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestMatchShortCircuit(t *testing.T) {
+	args := map[string]interface{}{"value": big.NewInt(1)}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"value > 1 && missing == 1", false},
+		{"value == 1 || missing == 1", true},
+		{"false && 'a' < 1", false},
+		{"true || 'a' && 1", true},
+	}
+	for _, tt := range tests {
+		got, err := mustParse(t, tt.source).Match(args)
+		if err != nil {
+			t.Errorf("Match(%q) error: %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+
+	if _, err := mustParse(t, "value == 1 && missing == 1").Match(args); err == nil {
+		t.Errorf("expected an error for unknown argument")
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	args := map[string]interface{}{
+		"value":  big.NewInt(1),
+		"symbol": "USDC",
+	}
+
+	tests := []string{
+		"value",
+		"symbol == 1",
+		"value && true",
+		"!value",
+		"true < false",
+	}
+	for _, source := range tests {
+		if _, err := mustParse(t, source).Match(args); err == nil {
+			t.Errorf("Match(%q) expected an error", source)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"value >",
+		"value > 1 &&",
+		"(value > 1",
+		"value > 1)",
+		"value = 1",
+		"value > 0x",
+		"symbol == 'USDC",
+		"value > 1.5",
+		"value > 1e-3",
+		"value > 1.2.3",
+		"value $ 1",
+		"value > 1 value",
+	}
+	for _, source := range tests {
+		if _, err := Parse(source); err == nil {
+			t.Errorf("Parse(%q) expected an error", source)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	e := mustParse(t, "value > 1e24 && (from != 0x0 || !approved) && true")
+	want := []string{"value", "from", "approved"}
+	got := e.Identifiers()
+	if len(got) != len(want) {
+		t.Fatalf("Identifiers() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Identifiers() = %v, want %v", got, want)
+		}
+	}
+}
+
+func mustParse(t *testing.T, source string) *Expr {
+	t.Helper()
+	e, err := Parse(source)
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", source, err)
+	}
+	return e
+}
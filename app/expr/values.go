@@ -0,0 +1,68 @@
+package expr
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// hexLiteral is a 0x-prefixed literal, it compares numerically with addresses, bytes and integers.
+type hexLiteral string
+
+// toNumber converts a value to an integer. The second result tells whether
+// the value is numeric by nature, as opposed to a string that looks like a number.
+func toNumber(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, true
+	case hexLiteral:
+		n, ok := new(big.Int).SetString(strings.TrimPrefix(string(v), "0x"), 16)
+		if !ok {
+			return nil, false
+		}
+		return n, true
+	case common.Address:
+		return new(big.Int).SetBytes(v.Bytes()), true
+	case common.Hash:
+		return new(big.Int).SetBytes(v.Bytes()), true
+	case []byte:
+		return new(big.Int).SetBytes(v), true
+	case string:
+		return parseNumericString(v), false
+	case bool:
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()), true
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return new(big.Int).SetBytes(data), true
+		}
+	}
+	return nil, false
+}
+
+// parseNumericString accepts hex (e.g. hexified bytes or addresses) and decimal strings.
+func parseNumericString(s string) *big.Int {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n, ok := new(big.Int).SetString(s[2:], 16)
+		if ok {
+			return n
+		}
+		return nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if ok {
+		return n
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// discoveredAddress is an address carried by a factory event. It is watched right away,
+// and persisted once its block is confirmed.
+type discoveredAddress struct {
+	contract    types.Contract
+	address     ethcommon.Address
+	blockNumber uint64
+	blockHash   ethcommon.Hash
+}
+
+// discoverAddresses scans logs for factory events and starts watching the addresses
+// they carry. It returns true when any new address has been added.
+func (c *chain) discoverAddresses(logs []contractLog) bool {
+	discovered := false
+	for i := range logs {
+		log := &logs[i].Log
+		contract := logs[i].contract
+		if len(c.factories[contract.Name()]) == 0 {
+			continue
+		}
+		event, err := matchEvent(log, contract)
+		if err != nil {
+			continue
+		}
+		for _, child := range c.factories[contract.Name()] {
+			if child.Factory().EventName != event.Name {
+				continue
+			}
+			args, err := parseArgumentValues(log, contract.ABI(), event)
+			if err != nil {
+				c.logger.Warnw("Could not decode factory event", "contractName", contract.Name(), "eventName", event.Name, "err", err)
+				break
+			}
+			// Decoded addresses are normalized to checksummed hex strings.
+			value, ok := args[child.Factory().ArgName].(string)
+			if !ok || !ethcommon.IsHexAddress(value) {
+				continue
+			}
+			if address := ethcommon.HexToAddress(value); c.addAddress(child, address) {
+				c.logger.Infow("Discovered contract address", "contractName", child.Name(), "address", address, "blockNumber", log.BlockNumber)
+				c.discovered = append(c.discovered, &discoveredAddress{
+					contract:    child,
+					address:     address,
+					blockNumber: log.BlockNumber,
+					blockHash:   log.BlockHash,
+				})
+				discovered = true
+			}
+		}
+	}
+	return discovered
+}
+
+func (c *chain) addAddress(contract types.Contract, address ethcommon.Address) bool {
+	if _, exists := c.addressMap[address]; exists {
+		return false
+	}
+	// Contracts with own queries pick up the address from the contract itself.
+	if !hasContractQueries(contract) {
+		c.addresses = append(c.addresses, address)
+	}
+	c.addressMap[address] = contract
+	contract.AddAddress(address)
+	if c.mempool != nil && contract.HasCalls() && contract.HasCallType(CallTypeCall) {
+		c.mempool.watch(address, contract)
+	}
+	common.PromConfiguredAddresses.WithLabelValues(c.name, contract.Name()).Inc()
+	return true
+}
+
+// trackDiscoveredAddresses hands addresses discovered in the processed range over to their blocks
+// in the reorg window, they are saved with confirmed blocks. Addresses of blocks that changed since
+// the discovery are dropped, they are discovered again if the new block has the factory event too.
+func (c *chain) trackDiscoveredAddresses(ctx context.Context, headers map[uint64]*rpcBlock) {
+	var pending []*discoveredAddress
+	for _, discovered := range c.discovered {
+		header, exists := headers[discovered.blockNumber]
+		switch {
+		case !exists:
+			pending = append(pending, discovered)
+		case header.Hash != discovered.blockHash:
+			c.removeAddress(ctx, discovered, false)
+		default:
+			if block := c.window.addAddress(discovered); block == nil || block.confirmed {
+				c.saveAddress(ctx, discovered)
+			}
+		}
+	}
+	c.discovered = pending
+}
+
+func (c *chain) saveAddress(ctx context.Context, discovered *discoveredAddress) {
+	if err := c.checkpoints.SaveAddress(ctx, c.name, discovered.contract.Name(), discovered.address); err != nil {
+		c.logger.Errorw("Failed to save discovered address", "contractName", discovered.contract.Name(), "address", discovered.address, "err", err)
+	}
+}
+
+// removeAddress stops watching an address discovered in an orphaned block, saved addresses are removed from checkpoints.
+func (c *chain) removeAddress(ctx context.Context, discovered *discoveredAddress, saved bool) {
+	contract, address := discovered.contract, discovered.address
+	c.logger.Warnw("Dropping address discovered in an orphaned block", "contractName", contract.Name(), "address", address, "blockNumber", discovered.blockNumber)
+	common.PromAddressesRetracted.WithLabelValues(c.name, contract.Name()).Inc()
+
+	delete(c.addressMap, address)
+	var addresses []ethcommon.Address
+	for _, a := range c.addresses {
+		if a != address {
+			addresses = append(addresses, a)
+		}
+	}
+	c.addresses = addresses
+	contract.RemoveAddress(address)
+	if c.mempool != nil {
+		c.mempool.unwatch(address)
+	}
+
+	if saved {
+		if err := c.checkpoints.RemoveAddress(ctx, c.name, contract.Name(), address); err != nil {
+			c.logger.Errorw("Failed to remove discovered address", "contractName", contract.Name(), "address", address, "err", err)
+		}
+	}
+}
+
+// dropDiscoveredAddresses drops addresses discovered above the last processed block, e.g. on a reorg.
+func (c *chain) dropDiscoveredAddresses(ctx context.Context) {
+	for _, discovered := range c.discovered {
+		c.removeAddress(ctx, discovered, false)
+	}
+	c.discovered = nil
+}
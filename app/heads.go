@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// headTracker notifies about new chain head block numbers, either from
+// a websocket subscription or by polling eth_blockNumber over HTTP.
+type headTracker struct {
+	heads  chan uint64
+	errs   chan error
+	cancel context.CancelFunc
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func newHeadTracker(ctx context.Context, client *chainClient, url string, pollInterval time.Duration) (*headTracker, error) {
+	if isHTTPURL(url) {
+		return newPollingHeadTracker(ctx, client, pollInterval), nil
+	}
+	return newSubscriptionHeadTracker(ctx, client)
+}
+
+func newSubscriptionHeadTracker(ctx context.Context, client *chainClient) (*headTracker, error) {
+	headersCh := make(chan *ethtypes.Header)
+	sub, err := client.SubscribeNewHead(ctx, headersCh)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t := &headTracker{
+		heads:  make(chan uint64),
+		errs:   make(chan error, 1),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-sub.Err():
+				t.errs <- err
+				return
+			case header := <-headersCh:
+				select {
+				case t.heads <- header.Number.Uint64():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return t, nil
+}
+
+func newPollingHeadTracker(ctx context.Context, client *chainClient, pollInterval time.Duration) *headTracker {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &headTracker{
+		heads:  make(chan uint64),
+		errs:   make(chan error, 1),
+		cancel: cancel,
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		var lastHead uint64
+		for {
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					t.errs <- err
+				}
+				return
+			}
+			if head > lastHead {
+				lastHead = head
+				select {
+				case t.heads <- head:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return t
+}
+
+func (t *headTracker) Heads() <-chan uint64 {
+	return t.heads
+}
+
+func (t *headTracker) Err() <-chan error {
+	return t.errs
+}
+
+func (t *headTracker) Close() {
+	t.cancel()
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// mempool tracks pending transactions sent to contracts with calls enabled. It is shared
+// between the chain loop and the pending transactions subscription, hence the mutex.
+type mempool struct {
+	chainName string
+	mu        sync.Mutex
+	contracts map[ethcommon.Address]types.Contract
+	seen      map[ethcommon.Hash]time.Time
+}
+
+func newMempool(chainName string) *mempool {
+	return &mempool{
+		chainName: chainName,
+		contracts: make(map[ethcommon.Address]types.Contract),
+		seen:      make(map[ethcommon.Hash]time.Time),
+	}
+}
+
+func (m *mempool) watch(address ethcommon.Address, contract types.Contract) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contracts[address] = contract
+}
+
+func (m *mempool) unwatch(address ethcommon.Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.contracts, address)
+}
+
+// match returns the watched contract the transaction is sent to, and remembers when it was seen.
+func (m *mempool) match(tx *rpcTransaction, seenAt time.Time) types.Contract {
+	if tx.To == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	contract, exists := m.contracts[*tx.To]
+	if !exists {
+		return nil
+	}
+	if _, seen := m.seen[tx.Hash]; seen {
+		return nil
+	}
+	m.seen[tx.Hash] = seenAt
+	return contract
+}
+
+// included observes the inclusion latency of a pending transaction once it is mined.
+func (m *mempool) included(txHash ethcommon.Hash, blockTs time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seenAt, exists := m.seen[txHash]
+	if !exists {
+		return
+	}
+	delete(m.seen, txHash)
+	latency := blockTs.Sub(seenAt)
+	if latency < 0 {
+		// Block timestamps have a resolution of seconds.
+		latency = 0
+	}
+	common.PromInclusionLatency.WithLabelValues(m.chainName).Observe(latency.Seconds())
+}
+
+// prune forgets transactions that were not mined in time, e.g. dropped or replaced.
+func (m *mempool) prune(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for txHash, seenAt := range m.seen {
+		if now.Sub(seenAt) > common.DefaultMempoolTTL {
+			delete(m.seen, txHash)
+		}
+	}
+}
+
+// watchMempool subscribes to pending transaction hashes, looks them up in batches and emits
+// pending calls for the ones sent to watched contracts. Failures are not fatal for the chain.
+func (c *chain) watchMempool(ctx context.Context, client *chainClient) {
+	hashes := make(chan ethcommon.Hash, common.DefaultRPCBatchSize)
+	sub, err := client.rpcClient.EthSubscribe(ctx, hashes, "newPendingTransactions")
+	if err != nil {
+		c.logger.Warnw("Pending transactions subscription failed", "err", err)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(common.DefaultMempoolInterval)
+	defer ticker.Stop()
+
+	var batch []ethcommon.Hash
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			c.logger.Warnw("Pending transactions subscription failed", "err", err)
+			return
+		case hash := <-hashes:
+			batch = append(batch, hash)
+		case now := <-ticker.C:
+			c.mempool.prune(now)
+			if len(batch) == 0 {
+				continue
+			}
+			txs, err := client.PendingTransactions(ctx, batch)
+			batch = nil
+			if err != nil {
+				c.logger.Warnw("Failed to get pending transactions", "err", err)
+				continue
+			}
+			for _, tx := range txs {
+				if contract := c.mempool.match(tx, now); contract != nil {
+					c.outputPendingCall(tx, contract, now)
+				}
+			}
+		}
+	}
+}
+
+func (c *chain) outputPendingCall(tx *rpcTransaction, contract types.Contract, seenAt time.Time) {
+	frame := &callFrame{
+		txHash:   tx.Hash,
+		callType: CallTypeCall,
+		from:     tx.From,
+		to:       *tx.To,
+		value:    (*big.Int)(tx.Value),
+		input:    tx.Input,
+		success:  true,
+	}
+	call, err := decodeCall(seenAt, &rpcBlock{}, frame, contract)
+	if err != nil {
+		common.PromCallsMalformed.WithLabelValues(c.name, contract.Name()).Inc()
+		c.logger.Debugw("Could not decode pending call", "contractName", contract.Name(), "txHash", tx.Hash, "err", err)
+		return
+	}
+	call.ChainID = c.chainID
+	call.Status = types.EventPending
+	encodeNumbers(call.MethodArgs, contract.NumberEncoding())
+	common.PromPendingCalls.WithLabelValues(c.name, contract.Name(), call.MethodName).Inc()
+	c.outputs.WriteCall(call)
+}
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/pinebit/lognite/app/types"
 	"go.uber.org/zap"
 )
@@ -16,9 +17,78 @@ func NewLoggerOutput(logger *zap.SugaredLogger) types.Output {
 }
 
 func (o loggerOutput) Write(event *types.Event) {
+	o.logger.Infow("Event", eventKeyValues(event)...)
+}
+
+func (o loggerOutput) Confirm(event *types.Event) {
+	o.logger.Infow("Confirmed event", eventKeyValues(event)...)
+}
+
+func (o loggerOutput) Retract(event *types.Event) {
+	o.logger.Warnw("Retracted event", eventKeyValues(event)...)
+}
+
+func (o loggerOutput) WriteCall(call *types.Call) {
+	if call.Status == types.EventPending {
+		o.logger.Infow("Pending call", callKeyValues(call)...)
+		return
+	}
+	o.logger.Infow("Call", callKeyValues(call)...)
+}
+
+func (o loggerOutput) RetractCall(call *types.Call) {
+	o.logger.Warnw("Retracted call", callKeyValues(call)...)
+}
+
+func (o loggerOutput) WriteState(state *types.State) {
+	o.logger.Infow("State", stateKeyValues(state)...)
+}
+
+func (o loggerOutput) WriteBlock(block *types.Block) {
+	o.logger.Infow("Block",
+		".chainName", block.ChainName,
+		".chainId", block.ChainID,
+		".blockNumber", block.Number,
+		".blockHash", block.Hash,
+		".parentHash", block.ParentHash,
+		".blockTs", block.BlockTs,
+		".gasUsed", block.GasUsed,
+		".gasLimit", block.GasLimit,
+		".baseFee", block.BaseFee,
+		".txCount", block.TxCount,
+		".miner", block.Miner)
+}
+
+func (o loggerOutput) RetractBlock(block *types.Block) {
+	o.logger.Warnw("Retracted block",
+		".chainName", block.ChainName,
+		".chainId", block.ChainID,
+		".blockNumber", block.Number,
+		".blockHash", block.Hash)
+}
+
+func (o loggerOutput) WriteRawLog(log *types.RawLog) {
+	o.logger.Warnw("Raw log",
+		".chainName", log.Contract.ChainName(),
+		".chainId", log.ChainID,
+		".contractName", log.Contract.Name(),
+		".contractAddress", log.Address,
+		".topics", log.Topics,
+		".data", hexutil.Encode(log.Data),
+		".blockTs", log.BlockTs,
+		".blockNumber", log.BlockNumber,
+		".blockHash", log.BlockHash,
+		".txHash", log.TxHash,
+		".txIndex", log.TxIndex,
+		".logIndex", log.LogIndex,
+		".error", log.Error)
+}
+
+func eventKeyValues(event *types.Event) []interface{} {
 	var kv []interface{}
 
 	kv = append(kv, ".chainName", event.Contract.ChainName())
+	kv = append(kv, ".chainId", event.ChainID)
 	kv = append(kv, ".contractName", event.Contract.Name())
 	kv = append(kv, ".contractAddress", event.Address)
 	kv = append(kv, ".eventName", event.EventName)
@@ -28,10 +98,67 @@ func (o loggerOutput) Write(event *types.Event) {
 	kv = append(kv, ".txHash", event.TxHash)
 	kv = append(kv, ".txIndex", event.TxIndex)
 	kv = append(kv, ".logIndex", event.LogIndex)
+	kv = append(kv, ".status", event.Status)
+
+	if event.Tx != nil {
+		kv = append(kv, ".txFrom", event.Tx.From)
+		kv = append(kv, ".txTo", event.Tx.To)
+		kv = append(kv, ".txValue", event.Tx.Value)
+		kv = append(kv, ".txGasUsed", event.Tx.GasUsed)
+		kv = append(kv, ".txEffectiveGasPrice", event.Tx.EffectiveGasPrice)
+		kv = append(kv, ".txSelector", event.Tx.Selector)
+	}
 
 	for ak, av := range event.EventArgs {
 		kv = append(kv, ak, av)
 	}
 
-	o.logger.Infow("Event", kv...)
+	return kv
+}
+
+func callKeyValues(call *types.Call) []interface{} {
+	var kv []interface{}
+
+	kv = append(kv, ".chainName", call.Contract.ChainName())
+	kv = append(kv, ".chainId", call.ChainID)
+	kv = append(kv, ".contractName", call.Contract.Name())
+	kv = append(kv, ".contractAddress", call.Address)
+	kv = append(kv, ".methodName", call.MethodName)
+	kv = append(kv, ".callType", call.CallType)
+	kv = append(kv, ".traceAddress", call.TraceAddress)
+	kv = append(kv, ".from", call.From)
+	kv = append(kv, ".value", call.Value)
+	kv = append(kv, ".success", call.Success)
+	kv = append(kv, ".status", call.Status)
+	kv = append(kv, ".blockTs", call.BlockTs)
+	kv = append(kv, ".blockNumber", call.BlockNumber)
+	kv = append(kv, ".blockHash", call.BlockHash)
+	kv = append(kv, ".txHash", call.TxHash)
+	kv = append(kv, ".txIndex", call.TxIndex)
+
+	for ak, av := range call.MethodArgs {
+		kv = append(kv, ak, av)
+	}
+
+	return kv
+}
+
+func stateKeyValues(state *types.State) []interface{} {
+	var kv []interface{}
+
+	kv = append(kv, ".chainName", state.Contract.ChainName())
+	kv = append(kv, ".chainId", state.ChainID)
+	kv = append(kv, ".contractName", state.Contract.Name())
+	kv = append(kv, ".contractAddress", state.Address)
+	kv = append(kv, ".methodName", state.MethodName)
+	kv = append(kv, ".methodArgs", state.MethodArgs)
+	kv = append(kv, ".blockTs", state.BlockTs)
+	kv = append(kv, ".blockNumber", state.BlockNumber)
+	kv = append(kv, ".blockHash", state.BlockHash)
+
+	for ak, av := range state.Values {
+		kv = append(kv, ak, av)
+	}
+
+	return kv
 }
@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/pinebit/lognite/app/common"
@@ -18,16 +21,26 @@ import (
 type Postgres interface {
 	types.Service
 	types.Output
+	types.Checkpoints
 
 	Connect(ctx context.Context, url string) error
 	Close() error
 	MigrateSchema(ctx context.Context, contracts types.ContractsPerChain) error
+	MigrateBlocksSchema(ctx context.Context, chainName string) error
+	// RedecodeEvents rewrites event names and args of the stored events of the contract,
+	// RedecodeRawLogs moves raw logs of the contract that now decode to its events table.
+	// Both return the number of changed rows.
+	RedecodeEvents(ctx context.Context, contract types.Contract, decode DecodeFunc) (int, error)
+	RedecodeRawLogs(ctx context.Context, contract types.Contract, decode DecodeFunc) (int, error)
 }
 
+// DecodeFunc decodes a stored log with the current ABI, logs decoded to nil events are left as stored.
+type DecodeFunc func(blockTs time.Time, log *ethtypes.Log) (*types.Event, error)
+
 type postgres struct {
 	db        *sqlx.DB
 	logger    *zap.SugaredLogger
-	queue     chan *types.Event
+	queue     chan func(ctx context.Context)
 	retention time.Duration
 	lastPrune time.Time
 }
@@ -37,9 +50,10 @@ var (
 )
 
 func NewPostgres(logger *zap.SugaredLogger, retention time.Duration) Postgres {
+	common.PromQueueDiscarded.WithLabelValues("postgres")
 	return &postgres{
 		logger:    logger.Named("postgres"),
-		queue:     make(chan *types.Event, common.DefaultPosgresQueueCapacity),
+		queue:     make(chan func(ctx context.Context), common.DefaultPosgresQueueCapacity),
 		retention: retention,
 		lastPrune: time.Now().Add(-common.DefaultPostgresPruneInterval),
 	}
@@ -75,11 +89,11 @@ func (d postgres) Run(ctx context.Context, done func()) {
 		select {
 		case <-ctx.Done():
 			return
-		case event, ok := <-d.queue:
+		case handle, ok := <-d.queue:
 			if !ok {
 				return
 			}
-			d.handleEvent(ctx, event)
+			handle(ctx)
 		}
 	}
 }
@@ -94,6 +108,28 @@ func (d postgres) MigrateSchema(ctx context.Context, contracts types.ContractsPe
 		return err
 	}
 
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS lognite_checkpoints (
+									chain_name TEXT PRIMARY KEY,
+									block_number NUMERIC NOT NULL,
+									block_hash TEXT NOT NULL,
+									updated_at TIMESTAMPTZ NOT NULL);`)
+	if err != nil {
+		d.logger.Errorw("Postgres failed to create checkpoints table", "err", err)
+		defer tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS lognite_discovered_addresses (
+									chain_name TEXT NOT NULL,
+									contract_name TEXT NOT NULL,
+									address TEXT NOT NULL,
+									PRIMARY KEY (chain_name, contract_name, address));`)
+	if err != nil {
+		d.logger.Errorw("Postgres failed to create discovered addresses table", "err", err)
+		defer tx.Rollback()
+		return err
+	}
+
 	for chainName, chainContracts := range contracts {
 		_, err := tx.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+chainName)
 		if err != nil {
@@ -122,7 +158,29 @@ func (d postgres) MigrateSchema(ctx context.Context, contracts types.ContractsPe
 				return err
 			}
 
-			columns := []string{"block_ts", "event"}
+			// Columns added after the initial schema, so that existing tables get upgraded.
+			addedColumns := []string{
+				"status TEXT NOT NULL DEFAULT 'confirmed'",
+				"chain_id NUMERIC",
+				"tx_from TEXT",
+				"tx_to TEXT",
+				"tx_value NUMERIC",
+				"tx_gas_used NUMERIC",
+				"tx_effective_gas_price NUMERIC",
+				"tx_selector TEXT",
+				"topics JSONB",
+				"data TEXT",
+			}
+			for _, column := range addedColumns {
+				q := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s;", tableName, column)
+				if _, err := tx.ExecContext(ctx, q); err != nil {
+					d.logger.Errorw("Postgres failed to add column", "err", err, "q", q)
+					defer tx.Rollback()
+					return err
+				}
+			}
+
+			columns := []string{"block_ts", "event", "address"}
 			for _, column := range columns {
 				if err := d.createIndex(ctx, tx, contract, column); err != nil {
 					d.logger.Errorw("Postgres failed to create index for column", "err", err, "tableName", tableName, "column", column)
@@ -130,19 +188,309 @@ func (d postgres) MigrateSchema(ctx context.Context, contracts types.ContractsPe
 					return err
 				}
 			}
+
+			// A log is identified by its block hash and index, which makes re-ingested events upserts.
+			q = fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s_log_idx ON %s (block_hash, log_index);", contract.Name(), tableName)
+			if _, err := tx.ExecContext(ctx, q); err != nil {
+				d.logger.Errorw("Postgres failed to create unique index", "err", err, "q", q)
+				defer tx.Rollback()
+				return err
+			}
+
+			if contract.HasCalls() {
+				if err := d.migrateCallsTable(ctx, tx, contract); err != nil {
+					defer tx.Rollback()
+					return err
+				}
+			}
+			if contract.HasRawLogs() {
+				if err := d.migrateRawLogsTable(ctx, tx, contract); err != nil {
+					defer tx.Rollback()
+					return err
+				}
+			}
+			if len(contract.StateQueries()) > 0 {
+				if err := d.migrateStateTable(ctx, tx, contract); err != nil {
+					defer tx.Rollback()
+					return err
+				}
+			}
 		}
 	}
 
 	return tx.Commit()
 }
 
+func (d postgres) MigrateBlocksSchema(ctx context.Context, chainName string) error {
+	if d.db == nil {
+		return errPostgresClosed
+	}
+
+	tableName := blocksTableQN(chainName)
+	schema := `block_number NUMERIC NOT NULL,
+				block_hash TEXT PRIMARY KEY,
+				parent_hash TEXT NOT NULL,
+				block_ts TIMESTAMPTZ,
+				gas_used NUMERIC NOT NULL,
+				gas_limit NUMERIC NOT NULL,
+				base_fee NUMERIC,
+				tx_count NUMERIC NOT NULL,
+				miner TEXT NOT NULL,
+				chain_id NUMERIC`
+	queries := []string{
+		"CREATE SCHEMA IF NOT EXISTS " + chainName,
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", tableName, schema),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS blocks_block_number_idx ON %s (block_number);", tableName),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS blocks_block_ts_idx ON %s (block_ts);", tableName),
+	}
+	for _, q := range queries {
+		if _, err := d.db.ExecContext(ctx, q); err != nil {
+			d.logger.Errorw("Postgres failed to migrate blocks table", "err", err, "q", q)
+			return err
+		}
+	}
+	return nil
+}
+
+func (d postgres) migrateCallsTable(ctx context.Context, tx *sql.Tx, contract types.Contract) error {
+	tableName := callsTableQN(contract)
+	schema := `id BIGSERIAL PRIMARY KEY,
+				block_ts TIMESTAMPTZ,
+				address TEXT NOT NULL,
+				method TEXT NOT NULL,
+				args JSONB NOT NULL,
+				tx_from TEXT NOT NULL,
+				tx_value NUMERIC,
+				success BOOLEAN NOT NULL,
+				tx_hash TEXT NOT NULL,
+				tx_index NUMERIC NOT NULL,
+				block_number NUMERIC NOT NULL,
+				block_hash TEXT NOT NULL,
+				chain_id NUMERIC,
+				call_type TEXT,
+				trace_address TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL DEFAULT 'confirmed'`
+	queries := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", tableName, schema),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_calls_block_ts_idx ON %s (block_ts);", contract.Name(), tableName),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_calls_method_idx ON %s (method);", contract.Name(), tableName),
+		// A call is identified by its transaction and position in the call tree, which makes re-ingested calls upserts.
+		fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s_call_trace_idx ON %s (block_hash, tx_hash, trace_address);", contract.Name(), tableName),
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			d.logger.Errorw("Postgres failed to migrate calls table", "err", err, "q", q)
+			return err
+		}
+	}
+	return nil
+}
+
+func (d postgres) migrateStateTable(ctx context.Context, tx *sql.Tx, contract types.Contract) error {
+	tableName := stateTableQN(contract)
+	schema := `id BIGSERIAL PRIMARY KEY,
+				block_ts TIMESTAMPTZ,
+				address TEXT NOT NULL,
+				method TEXT NOT NULL,
+				args JSONB NOT NULL,
+				result JSONB NOT NULL,
+				block_number NUMERIC NOT NULL,
+				block_hash TEXT NOT NULL,
+				chain_id NUMERIC`
+	queries := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", tableName, schema),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_state_block_ts_idx ON %s (block_ts);", contract.Name(), tableName),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_state_method_idx ON %s (method);", contract.Name(), tableName),
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			d.logger.Errorw("Postgres failed to migrate state table", "err", err, "q", q)
+			return err
+		}
+	}
+	return nil
+}
+
+func (d postgres) migrateRawLogsTable(ctx context.Context, tx *sql.Tx, contract types.Contract) error {
+	tableName := rawLogsTableQN(contract)
+	schema := `id BIGSERIAL PRIMARY KEY,
+				block_ts TIMESTAMPTZ,
+				address TEXT NOT NULL,
+				topics JSONB NOT NULL,
+				data TEXT NOT NULL,
+				error TEXT NOT NULL,
+				tx_hash TEXT NOT NULL,
+				tx_index NUMERIC NOT NULL,
+				block_number NUMERIC NOT NULL,
+				block_hash TEXT NOT NULL,
+				log_index NUMERIC NOT NULL,
+				chain_id NUMERIC`
+	queries := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", tableName, schema),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_raw_logs_block_ts_idx ON %s (block_ts);", contract.Name(), tableName),
+		fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s_raw_log_idx ON %s (block_hash, log_index);", contract.Name(), tableName),
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			d.logger.Errorw("Postgres failed to migrate raw logs table", "err", err, "q", q)
+			return err
+		}
+	}
+	return nil
+}
+
 func (d postgres) Write(event *types.Event) {
-	if len(d.queue) < common.DefaultPosgresQueueCapacity {
-		d.queue <- event
-	} else {
-		common.PromQueueDiscarded.WithLabelValues("postgres").Inc()
-		d.logger.Errorw("Postgres queue is full, discarding event")
+	d.enqueue(func(ctx context.Context) {
+		d.handleEvent(ctx, event)
+	})
+}
+
+func (d postgres) Confirm(event *types.Event) {
+	d.enqueue(func(ctx context.Context) {
+		d.updateStatus(ctx, event)
+	})
+}
+
+func (d postgres) Retract(event *types.Event) {
+	d.enqueue(func(ctx context.Context) {
+		d.updateStatus(ctx, event)
+	})
+}
+
+// WriteCall keeps pending calls out of the calls table, they would never be linked to the mined
+// calls and stay pending when dropped. Their inclusion latency is reported by metrics instead.
+func (d postgres) WriteCall(call *types.Call) {
+	if call.Status == types.EventPending {
+		return
 	}
+	d.enqueue(func(ctx context.Context) {
+		d.handleCall(ctx, call)
+	})
+}
+
+func (d postgres) RetractCall(call *types.Call) {
+	d.enqueue(func(ctx context.Context) {
+		d.updateCallStatus(ctx, call)
+	})
+}
+
+func (d postgres) WriteState(state *types.State) {
+	d.enqueue(func(ctx context.Context) {
+		d.handleState(ctx, state)
+	})
+}
+
+func (d postgres) WriteBlock(block *types.Block) {
+	d.enqueue(func(ctx context.Context) {
+		tableName := blocksTableQN(block.ChainName)
+		d.insertBlock(ctx, tableName, block)
+		d.pruneEvents(ctx, tableName)
+	})
+}
+
+// RetractBlock deletes the orphaned block, so that the table has one block per block number.
+func (d postgres) RetractBlock(block *types.Block) {
+	d.enqueue(func(ctx context.Context) {
+		d.deleteBlock(ctx, blocksTableQN(block.ChainName), block)
+	})
+}
+
+func (d postgres) WriteRawLog(log *types.RawLog) {
+	d.enqueue(func(ctx context.Context) {
+		tableName := rawLogsTableQN(log.Contract)
+		d.insertRawLog(ctx, tableName, log)
+		d.pruneEvents(ctx, tableName)
+	})
+}
+
+func (d postgres) LoadCheckpoint(ctx context.Context, chainName string) (*types.Checkpoint, error) {
+	if d.db == nil {
+		return nil, errPostgresClosed
+	}
+
+	var blockNumber uint64
+	var blockHash string
+	row := d.db.QueryRowContext(ctx, "SELECT block_number, block_hash FROM lognite_checkpoints WHERE chain_name = $1;", chainName)
+	if err := row.Scan(&blockNumber, &blockHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &types.Checkpoint{
+		BlockNumber: blockNumber,
+		BlockHash:   ethcommon.HexToHash(blockHash),
+	}, nil
+}
+
+// SaveCheckpoint goes through the same queue as events, so a checkpoint is never
+// persisted ahead of the events that precede it.
+func (d postgres) SaveCheckpoint(ctx context.Context, chainName string, checkpoint types.Checkpoint) error {
+	d.enqueue(func(ctx context.Context) {
+		q := `INSERT INTO lognite_checkpoints (chain_name, block_number, block_hash, updated_at)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (chain_name) DO UPDATE SET block_number = $2, block_hash = $3, updated_at = $4;`
+		_, err := d.db.ExecContext(ctx, q, chainName, checkpoint.BlockNumber, checkpoint.BlockHash.Hex(), time.Now())
+		if err != nil {
+			common.PromPostgresErrors.WithLabelValues("lognite_checkpoints").Inc()
+			d.logger.Errorw("Postgres failed to save checkpoint", "err", err, "chainName", chainName)
+		}
+	})
+	return nil
+}
+
+func (d postgres) LoadAddresses(ctx context.Context, chainName, contractName string) ([]ethcommon.Address, error) {
+	if d.db == nil {
+		return nil, errPostgresClosed
+	}
+
+	var hexAddresses []string
+	q := "SELECT address FROM lognite_discovered_addresses WHERE chain_name = $1 AND contract_name = $2;"
+	if err := d.db.SelectContext(ctx, &hexAddresses, q, chainName, contractName); err != nil {
+		return nil, err
+	}
+	var addresses []ethcommon.Address
+	for _, hexAddress := range hexAddresses {
+		addresses = append(addresses, ethcommon.HexToAddress(hexAddress))
+	}
+	return addresses, nil
+}
+
+// SaveAddress is queued like SaveCheckpoint, so it is persisted before the checkpoint that follows it.
+func (d postgres) SaveAddress(ctx context.Context, chainName, contractName string, address ethcommon.Address) error {
+	d.enqueue(func(ctx context.Context) {
+		q := `INSERT INTO lognite_discovered_addresses (chain_name, contract_name, address)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING;`
+		_, err := d.db.ExecContext(ctx, q, chainName, contractName, address.Hex())
+		if err != nil {
+			common.PromPostgresErrors.WithLabelValues("lognite_discovered_addresses").Inc()
+			d.logger.Errorw("Postgres failed to save discovered address", "err", err, "chainName", chainName, "contractName", contractName)
+		}
+	})
+	return nil
+}
+
+func (d postgres) RemoveAddress(ctx context.Context, chainName, contractName string, address ethcommon.Address) error {
+	d.enqueue(func(ctx context.Context) {
+		q := "DELETE FROM lognite_discovered_addresses WHERE chain_name = $1 AND contract_name = $2 AND address = $3;"
+		_, err := d.db.ExecContext(ctx, q, chainName, contractName, address.Hex())
+		if err != nil {
+			common.PromPostgresErrors.WithLabelValues("lognite_discovered_addresses").Inc()
+			d.logger.Errorw("Postgres failed to remove discovered address", "err", err, "chainName", chainName, "contractName", contractName)
+		}
+	})
+	return nil
+}
+
+// enqueue blocks when the queue is full, applying backpressure to the chains
+// instead of discarding data behind the checkpoint. The discarded counter is
+// still exported for existing dashboards, it stays at zero.
+func (d postgres) enqueue(handle func(ctx context.Context)) {
+	if len(d.queue) == common.DefaultPosgresQueueCapacity {
+		common.PromQueueFull.WithLabelValues("postgres").Inc()
+	}
+	d.queue <- handle
 }
 
 func (d postgres) handleEvent(ctx context.Context, event *types.Event) {
@@ -151,6 +499,18 @@ func (d postgres) handleEvent(ctx context.Context, event *types.Event) {
 	d.pruneEvents(ctx, tableName)
 }
 
+func (d postgres) handleCall(ctx context.Context, call *types.Call) {
+	tableName := callsTableQN(call.Contract)
+	d.insertCall(ctx, tableName, call)
+	d.pruneEvents(ctx, tableName)
+}
+
+func (d postgres) handleState(ctx context.Context, state *types.State) {
+	tableName := stateTableQN(state.Contract)
+	d.insertState(ctx, tableName, state)
+	d.pruneEvents(ctx, tableName)
+}
+
 func (d postgres) createIndex(ctx context.Context, tx *sql.Tx, contract types.Contract, column string) error {
 	q := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s);", contract.Name(), column, eventsTableQN(contract), column)
 	_, err := tx.ExecContext(ctx, q)
@@ -162,11 +522,11 @@ func (d postgres) insertRecord(ctx context.Context, tableName string, event *typ
 	if err != nil {
 		d.logger.Errorw("Failed marshal json record", "err", err)
 	} else {
-		q := fmt.Sprintf(`INSERT INTO %s (block_ts, address, event, args, tx_hash, tx_index, block_number, block_hash, log_index) 
-						  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, tableName)
-		_, err = d.db.ExecContext(
-			ctx,
-			q,
+		q := fmt.Sprintf(`INSERT INTO %s (block_ts, address, event, args, tx_hash, tx_index, block_number, block_hash, log_index, status, chain_id,
+							topics, data, tx_from, tx_to, tx_value, tx_gas_used, tx_effective_gas_price, tx_selector) 
+						  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+						  ON CONFLICT (block_hash, log_index) DO UPDATE SET status = EXCLUDED.status`, tableName)
+		args := []interface{}{
 			event.BlockTs,
 			event.Address.Hex(),
 			event.EventName,
@@ -175,7 +535,13 @@ func (d postgres) insertRecord(ctx context.Context, tableName string, event *typ
 			event.TxIndex,
 			event.BlockNumber,
 			event.BlockHash.Hex(),
-			event.LogIndex)
+			event.LogIndex,
+			event.Status,
+			event.ChainID,
+			topicsJSON(event.Topics),
+			hexutil.Encode(event.Data),
+		}
+		_, err = d.db.ExecContext(ctx, q, append(args, transactionValues(event.Tx)...)...)
 		if err != nil {
 			common.PromPostgresErrors.WithLabelValues(tableName).Inc()
 			d.logger.Errorw("Postgres failed to insert", "err", err, "q", q)
@@ -185,8 +551,171 @@ func (d postgres) insertRecord(ctx context.Context, tableName string, event *typ
 	}
 }
 
+func (d postgres) insertCall(ctx context.Context, tableName string, call *types.Call) {
+	jsonb, err := json.Marshal(call.MethodArgs)
+	if err != nil {
+		d.logger.Errorw("Failed marshal json record", "err", err)
+		return
+	}
+	var value interface{}
+	if call.Value != nil {
+		value = call.Value.String()
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (block_ts, address, method, args, tx_from, tx_value, success, tx_hash, tx_index, block_number, block_hash, chain_id,
+						call_type, trace_address, status)
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+					  ON CONFLICT DO NOTHING`, tableName)
+	_, err = d.db.ExecContext(
+		ctx,
+		q,
+		call.BlockTs,
+		call.Address.Hex(),
+		call.MethodName,
+		jsonb,
+		call.From.Hex(),
+		value,
+		call.Success,
+		call.TxHash.Hex(),
+		call.TxIndex,
+		call.BlockNumber,
+		call.BlockHash.Hex(),
+		call.ChainID,
+		call.CallType,
+		call.TraceAddress,
+		call.Status)
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to insert", "err", err, "q", q)
+	} else {
+		common.PromPostgresInserts.WithLabelValues(tableName).Inc()
+	}
+}
+
+func (d postgres) insertState(ctx context.Context, tableName string, state *types.State) {
+	args, err := json.Marshal(state.MethodArgs)
+	if err != nil {
+		d.logger.Errorw("Failed marshal json record", "err", err)
+		return
+	}
+	result, err := json.Marshal(state.Values)
+	if err != nil {
+		d.logger.Errorw("Failed marshal json record", "err", err)
+		return
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (block_ts, address, method, args, result, block_number, block_hash, chain_id)
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, tableName)
+	_, err = d.db.ExecContext(
+		ctx,
+		q,
+		state.BlockTs,
+		state.Address.Hex(),
+		state.MethodName,
+		args,
+		result,
+		state.BlockNumber,
+		state.BlockHash.Hex(),
+		state.ChainID)
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to insert", "err", err, "q", q)
+	} else {
+		common.PromPostgresInserts.WithLabelValues(tableName).Inc()
+	}
+}
+
+func (d postgres) insertBlock(ctx context.Context, tableName string, block *types.Block) {
+	var baseFee interface{}
+	if block.BaseFee != nil {
+		baseFee = block.BaseFee.String()
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (block_number, block_hash, parent_hash, block_ts, gas_used, gas_limit, base_fee, tx_count, miner, chain_id)
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+					  ON CONFLICT DO NOTHING`, tableName)
+	_, err := d.db.ExecContext(
+		ctx,
+		q,
+		block.Number,
+		block.Hash.Hex(),
+		block.ParentHash.Hex(),
+		block.BlockTs,
+		block.GasUsed,
+		block.GasLimit,
+		baseFee,
+		block.TxCount,
+		block.Miner.Hex(),
+		block.ChainID)
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to insert", "err", err, "q", q)
+	} else {
+		common.PromPostgresInserts.WithLabelValues(tableName).Inc()
+	}
+}
+
+func (d postgres) insertRawLog(ctx context.Context, tableName string, log *types.RawLog) {
+	q := fmt.Sprintf(`INSERT INTO %s (block_ts, address, topics, data, error, tx_hash, tx_index, block_number, block_hash, log_index, chain_id)
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+					  ON CONFLICT DO NOTHING`, tableName)
+	_, err := d.db.ExecContext(
+		ctx,
+		q,
+		log.BlockTs,
+		log.Address.Hex(),
+		topicsJSON(log.Topics),
+		hexutil.Encode(log.Data),
+		log.Error,
+		log.TxHash.Hex(),
+		log.TxIndex,
+		log.BlockNumber,
+		log.BlockHash.Hex(),
+		log.LogIndex,
+		log.ChainID)
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to insert", "err", err, "q", q)
+	} else {
+		common.PromPostgresInserts.WithLabelValues(tableName).Inc()
+	}
+}
+
+func (d postgres) updateStatus(ctx context.Context, event *types.Event) {
+	tableName := eventsTableQN(event.Contract)
+	q := fmt.Sprintf("UPDATE %s SET status = $1 WHERE block_hash = $2 AND log_index = $3;", tableName)
+	_, err := d.db.ExecContext(ctx, q, event.Status, event.BlockHash.Hex(), event.LogIndex)
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to update event status", "err", err, "q", q)
+	} else {
+		common.PromPostgresUpdates.WithLabelValues(tableName).Inc()
+	}
+}
+
+func (d postgres) updateCallStatus(ctx context.Context, call *types.Call) {
+	tableName := callsTableQN(call.Contract)
+	q := fmt.Sprintf("UPDATE %s SET status = $1 WHERE block_hash = $2 AND tx_hash = $3 AND trace_address = $4;", tableName)
+	_, err := d.db.ExecContext(ctx, q, call.Status, call.BlockHash.Hex(), call.TxHash.Hex(), call.TraceAddress)
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to update call status", "err", err, "q", q)
+	} else {
+		common.PromPostgresUpdates.WithLabelValues(tableName).Inc()
+	}
+}
+
+func (d postgres) deleteBlock(ctx context.Context, tableName string, block *types.Block) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE block_hash = $1;", tableName)
+	_, err := d.db.ExecContext(ctx, q, block.Hash.Hex())
+	if err != nil {
+		common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+		d.logger.Errorw("Postgres failed to delete block", "err", err, "q", q)
+	} else {
+		common.PromPostgresUpdates.WithLabelValues(tableName).Inc()
+	}
+}
+
+// pruneEvents deletes rows older than the retention, a zero retention keeps all rows.
 func (d *postgres) pruneEvents(ctx context.Context, tableName string) {
-	if time.Since(d.lastPrune) < common.DefaultPostgresPruneInterval {
+	if d.retention == 0 || time.Since(d.lastPrune) < common.DefaultPostgresPruneInterval {
 		return
 	}
 	d.lastPrune = time.Now()
@@ -198,6 +727,52 @@ func (d *postgres) pruneEvents(ctx context.Context, tableName string) {
 	}
 }
 
+// topicsJSON returns the topics as a JSON array of hex strings.
+func topicsJSON(topics []ethcommon.Hash) []byte {
+	if topics == nil {
+		topics = []ethcommon.Hash{}
+	}
+	data, _ := json.Marshal(topics)
+	return data
+}
+
+// transactionValues returns values of the tx_* columns, NULLs when the event is not enriched.
+func transactionValues(tx *types.Transaction) []interface{} {
+	if tx == nil {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	values := []interface{}{tx.From.Hex(), nil, nil, tx.GasUsed, nil, nil}
+	if tx.To != nil {
+		values[1] = tx.To.Hex()
+	}
+	if tx.Value != nil {
+		values[2] = tx.Value.String()
+	}
+	if tx.EffectiveGasPrice != nil {
+		values[4] = tx.EffectiveGasPrice.String()
+	}
+	if tx.Selector != "" {
+		values[5] = tx.Selector
+	}
+	return values
+}
+
 func eventsTableQN(contract types.Contract) string {
 	return fmt.Sprintf("%s.%s_events", contract.ChainName(), contract.Name())
 }
+
+func blocksTableQN(chainName string) string {
+	return fmt.Sprintf("%s.blocks", chainName)
+}
+
+func stateTableQN(contract types.Contract) string {
+	return fmt.Sprintf("%s.%s_state", contract.ChainName(), contract.Name())
+}
+
+func callsTableQN(contract types.Contract) string {
+	return fmt.Sprintf("%s.%s_calls", contract.ChainName(), contract.Name())
+}
+
+func rawLogsTableQN(contract types.Contract) string {
+	return fmt.Sprintf("%s.%s_raw_logs", contract.ChainName(), contract.Name())
+}
@@ -0,0 +1,170 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/jmoiron/sqlx"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// storedLog is a row of the events or raw logs table with the raw log it was decoded from.
+type storedLog struct {
+	ID          int64     `db:"id"`
+	BlockTs     time.Time `db:"block_ts"`
+	Address     string    `db:"address"`
+	Topics      []byte    `db:"topics"`
+	Data        string    `db:"data"`
+	TxHash      string    `db:"tx_hash"`
+	TxIndex     uint      `db:"tx_index"`
+	BlockNumber uint64    `db:"block_number"`
+	BlockHash   string    `db:"block_hash"`
+	LogIndex    uint      `db:"log_index"`
+	ChainID     *uint64   `db:"chain_id"`
+}
+
+const storedLogColumns = "id, block_ts, address, topics, data, tx_hash, tx_index, block_number, block_hash, log_index, chain_id"
+
+func (s storedLog) log() (*ethtypes.Log, error) {
+	var topics []ethcommon.Hash
+	if err := json.Unmarshal(s.Topics, &topics); err != nil {
+		return nil, fmt.Errorf("invalid topics: %v", err)
+	}
+	data, err := hexutil.Decode(s.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %v", err)
+	}
+	return &ethtypes.Log{
+		Address:     ethcommon.HexToAddress(s.Address),
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: s.BlockNumber,
+		TxHash:      ethcommon.HexToHash(s.TxHash),
+		TxIndex:     s.TxIndex,
+		BlockHash:   ethcommon.HexToHash(s.BlockHash),
+		Index:       s.LogIndex,
+	}, nil
+}
+
+// RedecodeEvents skips events stored before their raw logs were, they have no topics.
+func (d postgres) RedecodeEvents(ctx context.Context, contract types.Contract, decode DecodeFunc) (int, error) {
+	if d.db == nil {
+		return 0, errPostgresClosed
+	}
+
+	tableName := eventsTableQN(contract)
+	q := fmt.Sprintf("UPDATE %s SET event = $1, args = $2 WHERE id = $3;", tableName)
+	return d.redecodeBatches(ctx, tableName, "topics IS NOT NULL", decode, func(tx *sqlx.Tx, row *storedLog, event *types.Event) error {
+		args, err := json.Marshal(event.EventArgs)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, q, event.EventName, args, row.ID)
+		return err
+	})
+}
+
+func (d postgres) RedecodeRawLogs(ctx context.Context, contract types.Contract, decode DecodeFunc) (int, error) {
+	if d.db == nil {
+		return 0, errPostgresClosed
+	}
+
+	tableName := rawLogsTableQN(contract)
+	var exists bool
+	if err := d.db.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL;", tableName); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	// Raw logs are only written for confirmed blocks, and have no transaction details.
+	insert := fmt.Sprintf(`INSERT INTO %s (block_ts, address, event, args, tx_hash, tx_index, block_number, block_hash, log_index, status, chain_id, topics, data)
+						   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+						   ON CONFLICT (block_hash, log_index) DO NOTHING`, eventsTableQN(contract))
+	remove := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", tableName)
+	return d.redecodeBatches(ctx, tableName, "TRUE", decode, func(tx *sqlx.Tx, row *storedLog, event *types.Event) error {
+		args, err := json.Marshal(event.EventArgs)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			insert,
+			row.BlockTs,
+			row.Address,
+			event.EventName,
+			args,
+			row.TxHash,
+			row.TxIndex,
+			row.BlockNumber,
+			row.BlockHash,
+			row.LogIndex,
+			types.EventConfirmed,
+			row.ChainID,
+			row.Topics,
+			row.Data)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, remove, row.ID)
+		return err
+	})
+}
+
+// redecodeBatches decodes rows of the table matching the condition in batches of DefaultRedecodeBatchSize,
+// each batch is updated in a transaction. Rows that cannot be decoded are logged and left as stored.
+func (d postgres) redecodeBatches(ctx context.Context, tableName, condition string, decode DecodeFunc, update func(tx *sqlx.Tx, row *storedLog, event *types.Event) error) (int, error) {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE id > $1 AND %s ORDER BY id LIMIT $2;", storedLogColumns, tableName, condition)
+	var lastID int64
+	changed := 0
+	for {
+		var rows []storedLog
+		if err := d.db.SelectContext(ctx, &rows, q, lastID, common.DefaultRedecodeBatchSize); err != nil {
+			return changed, err
+		}
+		if len(rows) == 0 {
+			return changed, nil
+		}
+
+		tx, err := d.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return changed, err
+		}
+		updated := 0
+		for i := range rows {
+			row := &rows[i]
+			lastID = row.ID
+			log, err := row.log()
+			if err != nil {
+				d.logger.Warnw("Could not read stored log", "tableName", tableName, "id", row.ID, "err", err)
+				continue
+			}
+			event, err := decode(row.BlockTs, log)
+			if err != nil {
+				d.logger.Warnw("Could not re-decode stored log", "tableName", tableName, "id", row.ID, "err", err)
+				continue
+			}
+			if event == nil {
+				continue
+			}
+			if err := update(tx, row, event); err != nil {
+				defer tx.Rollback()
+				common.PromPostgresErrors.WithLabelValues(tableName).Inc()
+				return changed, err
+			}
+			updated++
+		}
+		if err := tx.Commit(); err != nil {
+			return changed, err
+		}
+		common.PromPostgresUpdates.WithLabelValues(tableName).Add(float64(updated))
+		changed += updated
+	}
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// contractLog is a log routed to the contract it is decoded with.
+type contractLog struct {
+	ethtypes.Log
+	contract types.Contract
+}
+
+// contractQuery filters logs of a single contract by topics: wildcard contracts
+// match from any address, and contracts with topic filters are not part of
+// the shared addresses query.
+type contractQuery struct {
+	contract types.Contract
+	topics   [][]ethcommon.Hash
+}
+
+func hasContractQueries(contract types.Contract) bool {
+	return contract.IsWildcard() || len(contract.TopicFilters()) > 0
+}
+
+// newContractQueries returns a query per event with topic filters, and
+// a single query matching signatures of the rest of allowed events.
+func newContractQueries(contract types.Contract) []contractQuery {
+	var eventNames []string
+	for eventName := range contract.ABI().Events {
+		eventNames = append(eventNames, eventName)
+	}
+	sort.Strings(eventNames)
+
+	var queries []contractQuery
+	var eventIDs []ethcommon.Hash
+	for _, eventName := range eventNames {
+		event := contract.ABI().Events[eventName]
+		if !contract.IsEventAllowed(event.Name) || event.Anonymous {
+			continue
+		}
+		if topics, exists := contract.TopicFilters()[event.Name]; exists {
+			queries = append(queries, contractQuery{
+				contract: contract,
+				topics:   append([][]ethcommon.Hash{{event.ID}}, topics...),
+			})
+		} else {
+			eventIDs = append(eventIDs, event.ID)
+		}
+	}
+	if len(eventIDs) > 0 {
+		queries = append(queries, contractQuery{
+			contract: contract,
+			topics:   [][]ethcommon.Hash{eventIDs},
+		})
+	}
+	return queries
+}
+
+// filterLogs pulls logs of the shared addresses query and of the contract queries,
+// ordered as they appear on chain. When factory events in the range add new
+// addresses, logs are pulled again to include those of the new addresses.
+func (c *chain) filterLogs(ctx context.Context, client *chainClient, fromBlockNumber, toBlockNumber uint64) ([]contractLog, error) {
+	for {
+		logs, err := c.filterAllLogs(ctx, client, fromBlockNumber, toBlockNumber)
+		if err != nil || !c.discoverAddresses(logs) {
+			return logs, err
+		}
+	}
+}
+
+func (c *chain) filterAllLogs(ctx context.Context, client *chainClient, fromBlockNumber, toBlockNumber uint64) ([]contractLog, error) {
+	fromBlock := new(big.Int).SetUint64(fromBlockNumber)
+	toBlock := new(big.Int).SetUint64(toBlockNumber)
+
+	var logs []contractLog
+	// An empty address list would match logs of the whole chain.
+	if len(c.addresses) > 0 {
+		addressLogs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			Addresses: c.addresses,
+			FromBlock: fromBlock,
+			ToBlock:   toBlock,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range addressLogs {
+			logs = append(logs, contractLog{
+				Log:      log,
+				contract: c.addressMap[log.Address],
+			})
+		}
+	}
+
+	for _, query := range c.queries {
+		addresses := query.contract.Addresses()
+		if !query.contract.IsWildcard() && len(addresses) == 0 {
+			continue
+		}
+		queryLogs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			Addresses: addresses,
+			Topics:    query.topics,
+			FromBlock: fromBlock,
+			ToBlock:   toBlock,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range queryLogs {
+			logs = append(logs, contractLog{
+				Log:      log,
+				contract: query.contract,
+			})
+		}
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs, nil
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"time"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// outputRawLog writes a log that could not be decoded. Raw logs of pending blocks are held
+// in the reorg window until confirmed, so that logs of orphaned blocks are never written.
+func (c chain) outputRawLog(log *ethtypes.Log, contract types.Contract, blockTs time.Time, err error) {
+	rawLog := &types.RawLog{
+		Contract:    contract,
+		ChainID:     c.chainID,
+		Address:     log.Address,
+		Topics:      log.Topics,
+		Data:        log.Data,
+		BlockTs:     blockTs,
+		BlockNumber: log.BlockNumber,
+		BlockHash:   log.BlockHash,
+		TxHash:      log.TxHash,
+		TxIndex:     log.TxIndex,
+		LogIndex:    log.Index,
+		Error:       err.Error(),
+	}
+	if log.BlockNumber > c.confirmedNumber {
+		c.window.addRawLog(rawLog)
+		return
+	}
+	c.writeRawLog(rawLog)
+}
+
+func (c chain) writeRawLog(rawLog *types.RawLog) {
+	common.PromRawLogs.WithLabelValues(c.name, rawLog.Contract.Name()).Inc()
+	c.outputs.WriteRawLog(rawLog)
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	out "github.com/pinebit/lognite/app/outputs"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// Redecode re-decodes logs of the contract stored in Postgres with its current ABI:
+// args of stored events are rewritten, and raw logs that now decode become events.
+func (a *app) Redecode(chainName, contractName string) error {
+	defer a.logger.Sync()
+
+	config, err := LoadConfig(a.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if config.Outputs.Postgres == nil {
+		return errors.New("'outputs.postgres' is not configured")
+	}
+
+	contracts, err := LoadContracts(config, path.Dir(a.configPath))
+	if err != nil {
+		return fmt.Errorf("failed to configure contracts: %v", err)
+	}
+	var contract types.Contract
+	for _, chainContract := range contracts[chainName] {
+		if chainContract.Name() == contractName {
+			contract = chainContract
+		}
+	}
+	if contract == nil {
+		return fmt.Errorf("chain '%s' has no contract '%s' configured", chainName, contractName)
+	}
+
+	rootCtx, cancel := context.WithCancel(context.Background())
+	go shutdownHandler(cancel)
+
+	pg := out.NewPostgres(a.logger, *config.Outputs.Postgres.Retention)
+	if err := pg.Connect(rootCtx, config.Outputs.Postgres.URL); err != nil {
+		return fmt.Errorf("failed to connect Postgres: url=%s", config.Outputs.Postgres.URL)
+	}
+	defer pg.Close()
+
+	if err := pg.MigrateSchema(rootCtx, types.ContractsPerChain{chainName: {contract}}); err != nil {
+		return fmt.Errorf("failed to migrate postgres schema: %v", err)
+	}
+
+	decode := func(blockTs time.Time, log *ethtypes.Log) (*types.Event, error) {
+		event, err := decodeEvent(blockTs, log, contract)
+		if event != nil {
+			encodeNumbers(event.EventArgs, contract.NumberEncoding())
+		}
+		return event, err
+	}
+
+	a.logger.Infow("Re-decoding stored events", "chainName", chainName, "contractName", contractName)
+	events, err := pg.RedecodeEvents(rootCtx, contract, decode)
+	if err != nil {
+		return fmt.Errorf("failed to re-decode events: %v", err)
+	}
+	rawLogs, err := pg.RedecodeRawLogs(rootCtx, contract, decode)
+	if err != nil {
+		return fmt.Errorf("failed to re-decode raw logs: %v", err)
+	}
+	a.logger.Infow("Re-decoded stored logs", "chainName", chainName, "contractName", contractName, "events", events, "rawLogs", rawLogs)
+	return nil
+}
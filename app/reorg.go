@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+type reorgBlock struct {
+	number    uint64
+	hash      ethcommon.Hash
+	events    []*types.Event
+	rawLogs   []*types.RawLog
+	calls     []*types.Call
+	record    *types.Block
+	addresses []*discoveredAddress
+	confirmed bool
+}
+
+// reorgWindow keeps hashes of recently processed blocks together with the events
+// emitted for them, so that a reorg can be rolled back to the common ancestor.
+// Only blocks with known headers are tracked: those with logs and range boundaries,
+// and every block when blocks are scanned.
+type reorgWindow struct {
+	size   uint64
+	blocks []*reorgBlock
+}
+
+func newReorgWindow(size uint64) *reorgWindow {
+	return &reorgWindow{
+		size: size,
+	}
+}
+
+// add tracks a processed block, blocks must be added in ascending order.
+func (w *reorgWindow) add(number uint64, hash ethcommon.Hash, confirmed bool) {
+	if n := len(w.blocks); n > 0 && w.blocks[n-1].number >= number {
+		return
+	}
+	w.blocks = append(w.blocks, &reorgBlock{
+		number:    number,
+		hash:      hash,
+		confirmed: confirmed,
+	})
+}
+
+// consistent tells whether a scanned block agrees with the tracked blocks: itself and its parent.
+func (w *reorgWindow) consistent(number uint64, hash, parentHash ethcommon.Hash) bool {
+	i := w.search(number)
+	if i > 0 && w.blocks[i-1].number+1 == number && w.blocks[i-1].hash != parentHash {
+		return false
+	}
+	return i == len(w.blocks) || w.blocks[i].number != number || w.blocks[i].hash == hash
+}
+
+// track returns the tracked block of a scanned block, inserting it in order if needed. Blocks below
+// the window are too deep to be reorged and are not tracked, the result is nil for them.
+func (w *reorgWindow) track(number uint64, hash ethcommon.Hash) *reorgBlock {
+	i := w.search(number)
+	if i < len(w.blocks) && w.blocks[i].number == number {
+		return w.blocks[i]
+	}
+	if i == 0 && len(w.blocks) > 0 {
+		return nil
+	}
+	block := &reorgBlock{
+		number:    number,
+		hash:      hash,
+		confirmed: true,
+	}
+	w.blocks = append(w.blocks, nil)
+	copy(w.blocks[i+1:], w.blocks[i:])
+	w.blocks[i] = block
+	return block
+}
+
+// search returns the index of the first tracked block not below the given block number.
+// find returns the tracked block of the given number, nil if it is not tracked.
+func (w *reorgWindow) find(number uint64) *reorgBlock {
+	if i := w.search(number); i < len(w.blocks) && w.blocks[i].number == number {
+		return w.blocks[i]
+	}
+	return nil
+}
+
+func (w *reorgWindow) search(number uint64) int {
+	return sort.Search(len(w.blocks), func(i int) bool { return w.blocks[i].number >= number })
+}
+
+func (w *reorgWindow) addEvent(event *types.Event) {
+	for i := len(w.blocks) - 1; i >= 0; i-- {
+		if w.blocks[i].number == event.BlockNumber {
+			w.blocks[i].events = append(w.blocks[i].events, event)
+			return
+		}
+	}
+}
+
+// addAddress tracks a discovered address in its block, which is returned, nil if the block is not tracked.
+func (w *reorgWindow) addAddress(discovered *discoveredAddress) *reorgBlock {
+	for i := len(w.blocks) - 1; i >= 0; i-- {
+		if w.blocks[i].number == discovered.blockNumber {
+			w.blocks[i].addresses = append(w.blocks[i].addresses, discovered)
+			return w.blocks[i]
+		}
+	}
+	return nil
+}
+
+// addRawLog holds a raw log of a pending block until the block is confirmed.
+func (w *reorgWindow) addRawLog(log *types.RawLog) {
+	for i := len(w.blocks) - 1; i >= 0; i-- {
+		if w.blocks[i].number == log.BlockNumber {
+			w.blocks[i].rawLogs = append(w.blocks[i].rawLogs, log)
+			return
+		}
+	}
+}
+
+// prune drops confirmed blocks that are too deep to be reorged, always keeping the latest one.
+func (w *reorgWindow) prune(lastBlockNumber uint64) {
+	i := 0
+	for i < len(w.blocks)-1 && w.blocks[i].confirmed && w.blocks[i].number+w.size <= lastBlockNumber {
+		i++
+	}
+	w.blocks = w.blocks[i:]
+}
+
+// confirm marks blocks up to the given block number as confirmed and returns the newly confirmed ones.
+func (w *reorgWindow) confirm(number uint64) []*reorgBlock {
+	var confirmed []*reorgBlock
+	for _, block := range w.blocks {
+		if block.number > number {
+			break
+		}
+		if !block.confirmed {
+			block.confirmed = true
+			confirmed = append(confirmed, block)
+		}
+	}
+	return confirmed
+}
+
+// lastConfirmed returns the latest confirmed block, or nil.
+func (w *reorgWindow) lastConfirmed() *reorgBlock {
+	for i := len(w.blocks) - 1; i >= 0; i-- {
+		if w.blocks[i].confirmed {
+			return w.blocks[i]
+		}
+	}
+	return nil
+}
+
+// rollback removes and returns the blocks above the given block number, newest first.
+func (w *reorgWindow) rollback(number uint64) []*reorgBlock {
+	var orphaned []*reorgBlock
+	for len(w.blocks) > 0 && w.blocks[len(w.blocks)-1].number > number {
+		orphaned = append(orphaned, w.blocks[len(w.blocks)-1])
+		w.blocks = w.blocks[:len(w.blocks)-1]
+	}
+	return orphaned
+}
+
+func (w *reorgWindow) reset() {
+	w.blocks = nil
+}
+
+// handleReorg finds the latest tracked block that is still canonical, retracts
+// events, calls, block records and discovered addresses of the orphaned blocks above it and rewinds the chain to re-ingest
+// the canonical branch. When no tracked block is canonical, all of them are retracted and the chain
+// rewinds below the window, data of orphaned blocks deeper than that cannot be retracted.
+func (c *chain) handleReorg(ctx context.Context, client *chainClient) error {
+	common.PromReorgs.WithLabelValues(c.name).Inc()
+
+	var blockNumbers []uint64
+	for _, block := range c.window.blocks {
+		blockNumbers = append(blockNumbers, block.number)
+	}
+	headers, err := client.HeadersByNumber(ctx, blockNumbers)
+	if err != nil {
+		return fmt.Errorf("call to HeadersByNumber failed: %w", err)
+	}
+
+	var ancestor *reorgBlock
+	for i := len(c.window.blocks) - 1; i >= 0; i-- {
+		if headers[c.window.blocks[i].number].Hash == c.window.blocks[i].hash {
+			ancestor = c.window.blocks[i]
+			break
+		}
+	}
+
+	if ancestor == nil {
+		common.PromReorgErrors.WithLabelValues(c.name).Inc()
+		rewindNumber := c.deepReorgNumber()
+		c.logger.Errorw("Reorg is deeper than the tracked window, rewinding below it", "blockNumber", c.lastBlockNumber, "rewindNumber", rewindNumber)
+		for _, block := range c.window.rollback(rewindNumber) {
+			c.retractBlock(ctx, block)
+		}
+		c.dropDiscoveredAddresses(ctx)
+		c.window.reset()
+		c.lastBlockNumber = rewindNumber
+		c.lastBlockHash = zeroHash
+		c.saveCheckpoint(ctx)
+		return nil
+	}
+
+	for _, block := range c.window.rollback(ancestor.number) {
+		c.retractBlock(ctx, block)
+	}
+	c.dropDiscoveredAddresses(ctx)
+
+	c.logger.Warnw("Reorg detected, rewinding to the common ancestor", "ancestorNumber", ancestor.number, "ancestorHash", ancestor.hash, "depth", c.lastBlockNumber-ancestor.number)
+	c.lastBlockNumber = ancestor.number
+	c.lastBlockHash = ancestor.hash
+	c.saveCheckpoint(ctx)
+	return nil
+}
+
+// deepReorgNumber returns the block to rewind to when the whole window is orphaned: below the oldest
+// tracked block and at least the window size below the last block, but not below the start block.
+func (c *chain) deepReorgNumber() uint64 {
+	var rewindNumber uint64
+	if c.lastBlockNumber > c.window.size {
+		rewindNumber = c.lastBlockNumber - c.window.size
+	}
+	if len(c.window.blocks) > 0 && c.window.blocks[0].number <= rewindNumber {
+		rewindNumber = c.window.blocks[0].number
+		if rewindNumber > 0 {
+			rewindNumber--
+		}
+	}
+	if c.startBlockNumber > 0 && rewindNumber < c.startBlockNumber-1 {
+		rewindNumber = c.startBlockNumber - 1
+	}
+	return rewindNumber
+}
+
+// retractBlock retracts everything emitted for an orphaned block, newest first, and
+// stops watching the addresses discovered in it.
+func (c *chain) retractBlock(ctx context.Context, block *reorgBlock) {
+	for i := len(block.events) - 1; i >= 0; i-- {
+		event := block.events[i]
+		common.PromEventsRetracted.WithLabelValues(c.name, event.Contract.Name()).Inc()
+		c.outputs.Retract(event)
+	}
+	for i := len(block.calls) - 1; i >= 0; i-- {
+		call := block.calls[i]
+		common.PromCallsRetracted.WithLabelValues(c.name, call.Contract.Name()).Inc()
+		c.outputs.RetractCall(call)
+	}
+	if block.record != nil {
+		common.PromBlocksRetracted.WithLabelValues(c.name).Inc()
+		c.outputs.RetractBlock(block.record)
+	}
+	for _, discovered := range block.addresses {
+		c.removeAddress(ctx, discovered, block.confirmed)
+	}
+}
@@ -0,0 +1,340 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pinebit/lognite/app/types"
+	"go.uber.org/zap"
+)
+
+// testHash returns the hash of a block on a fork.
+func testHash(number uint64, fork int64) ethcommon.Hash {
+	return ethcommon.BigToHash(new(big.Int).SetUint64(uint64(fork)<<32 | number))
+}
+
+func windowNumbers(w *reorgWindow) []uint64 {
+	var numbers []uint64
+	for _, block := range w.blocks {
+		numbers = append(numbers, block.number)
+	}
+	return numbers
+}
+
+func blockNumbers(blocks []*reorgBlock) []uint64 {
+	var numbers []uint64
+	for _, block := range blocks {
+		numbers = append(numbers, block.number)
+	}
+	return numbers
+}
+
+func assertNumbers(t *testing.T, name string, got, want []uint64) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestReorgWindow(t *testing.T) {
+	w := newReorgWindow(8)
+	w.add(10, testHash(10, 0), true)
+	w.add(12, testHash(12, 0), false)
+	w.add(12, testHash(12, 1), false)
+	w.add(11, testHash(11, 0), false)
+	w.add(14, testHash(14, 0), false)
+	assertNumbers(t, "add", windowNumbers(w), []uint64{10, 12, 14})
+	if w.blocks[1].hash != testHash(12, 0) {
+		t.Errorf("add replaced a tracked block")
+	}
+
+	consistent := []struct {
+		number     uint64
+		hash       ethcommon.Hash
+		parentHash ethcommon.Hash
+		want       bool
+	}{
+		{11, testHash(11, 0), testHash(10, 0), true},
+		{11, testHash(11, 1), testHash(10, 1), false},
+		{12, testHash(12, 0), testHash(11, 0), true},
+		{12, testHash(12, 1), testHash(11, 1), false},
+		{13, testHash(13, 1), testHash(12, 1), false},
+		{13, testHash(13, 0), testHash(12, 0), true},
+		{9, testHash(9, 1), testHash(8, 1), true},
+		{15, testHash(15, 0), testHash(14, 0), true},
+	}
+	for _, tt := range consistent {
+		if got := w.consistent(tt.number, tt.hash, tt.parentHash); got != tt.want {
+			t.Errorf("consistent(%d, %s) = %v, want %v", tt.number, tt.hash, got, tt.want)
+		}
+	}
+
+	if block := w.track(9, testHash(9, 0)); block != nil {
+		t.Errorf("track tracked a block below the window")
+	}
+	if block := w.track(12, testHash(12, 0)); block != w.blocks[1] {
+		t.Errorf("track did not return the tracked block")
+	}
+	w.track(13, testHash(13, 0))
+	w.track(11, testHash(11, 0))
+	assertNumbers(t, "track", windowNumbers(w), []uint64{10, 11, 12, 13, 14})
+	if !w.blocks[1].confirmed || !w.blocks[3].confirmed {
+		t.Errorf("scanned blocks are not confirmed")
+	}
+
+	assertNumbers(t, "confirm", blockNumbers(w.confirm(12)), []uint64{12})
+	assertNumbers(t, "confirm again", blockNumbers(w.confirm(12)), nil)
+	if block := w.lastConfirmed(); block == nil || block.number != 13 {
+		t.Errorf("lastConfirmed = %v, want 13", block)
+	}
+
+	assertNumbers(t, "rollback", blockNumbers(w.rollback(11)), []uint64{14, 13, 12})
+	assertNumbers(t, "after rollback", windowNumbers(w), []uint64{10, 11})
+
+	w.add(20, testHash(20, 0), false)
+	w.prune(20)
+	assertNumbers(t, "prune", windowNumbers(w), []uint64{20})
+	w.prune(40)
+	assertNumbers(t, "prune keeps the latest block", windowNumbers(w), []uint64{20})
+}
+
+type testOutput struct {
+	records []string
+}
+
+func (o *testOutput) Write(event *types.Event) {
+	o.records = append(o.records, fmt.Sprintf("event %d %s", event.BlockNumber, event.Status))
+}
+
+func (o *testOutput) Confirm(event *types.Event) {
+	o.records = append(o.records, fmt.Sprintf("confirm %d", event.BlockNumber))
+}
+
+func (o *testOutput) Retract(event *types.Event) {
+	o.records = append(o.records, fmt.Sprintf("retract %d %s", event.BlockNumber, event.Status))
+}
+
+func (o *testOutput) WriteCall(call *types.Call) {
+	o.records = append(o.records, fmt.Sprintf("call %d", call.BlockNumber))
+}
+
+func (o *testOutput) RetractCall(call *types.Call) {
+	o.records = append(o.records, fmt.Sprintf("retract call %d %s", call.BlockNumber, call.Status))
+}
+
+func (o *testOutput) WriteState(state *types.State) {}
+
+func (o *testOutput) WriteBlock(block *types.Block) {
+	o.records = append(o.records, fmt.Sprintf("block %d", block.Number))
+}
+
+func (o *testOutput) RetractBlock(block *types.Block) {
+	o.records = append(o.records, fmt.Sprintf("retract block %d", block.Number))
+}
+
+func (o *testOutput) WriteRawLog(log *types.RawLog) {}
+
+// headersRPC serves headers of the canonical chain, where blocks above forkNumber are on another fork.
+func headersRPC(t *testing.T, forkNumber uint64) func(req rpcRequest) (string, bool) {
+	return func(req rpcRequest) (string, bool) {
+		if req.Method != "eth_getBlockByNumber" || len(req.Params) == 0 {
+			return "", false
+		}
+		var param string
+		if err := json.Unmarshal(req.Params[0], &param); err != nil {
+			t.Errorf("invalid block number: %v", err)
+			return "", false
+		}
+		number, err := hexutil.DecodeUint64(param)
+		if err != nil {
+			t.Errorf("invalid block number: %v", err)
+			return "", false
+		}
+		fork := func(n uint64) int64 {
+			if n > forkNumber {
+				return 1
+			}
+			return 0
+		}
+		return fmt.Sprintf(`{"number": "%s", "hash": "%s", "parentHash": "%s", "timestamp": "0x0"}`,
+			hexutil.EncodeUint64(number), testHash(number, fork(number)), testHash(number-1, fork(number-1))), true
+	}
+}
+
+func TestHandleReorg(t *testing.T) {
+	tests := []struct {
+		name       string
+		forkNumber uint64
+		wantNumber uint64
+		wantHash   ethcommon.Hash
+		wantWindow []uint64
+		retracted  []uint64
+	}{
+		{"shallow", 12, 12, testHash(12, 0), []uint64{10, 11, 12}, []uint64{14, 13}},
+		{"deep", 5, 9, zeroHash, nil, []uint64{14, 13, 12, 11, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeRPC(t, headersRPC(t, tt.forkNumber))
+			defer server.Close()
+			ctx := context.Background()
+			client, err := dialChainClient(ctx, server.URL)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer client.Close()
+
+			output := &testOutput{}
+			checkpoints := NewFileCheckpoints(filepath.Join(t.TempDir(), "checkpoints.json"))
+			c := &chain{
+				name:            "test",
+				logger:          zap.NewNop().Sugar(),
+				outputs:         types.Outputs{output},
+				checkpoints:     checkpoints,
+				lastBlockNumber: 14,
+				lastBlockHash:   testHash(14, 0),
+				window:          newReorgWindow(4),
+			}
+			contract := types.NewContract("test", "token", &ethabi.ABI{}, nil, types.ContractOptions{})
+			for number := uint64(10); number <= 14; number++ {
+				c.window.add(number, testHash(number, 0), true)
+				block := c.window.blocks[len(c.window.blocks)-1]
+				block.events = []*types.Event{{Contract: contract, BlockNumber: number, Status: types.EventConfirmed}}
+				block.calls = []*types.Call{{Contract: contract, BlockNumber: number, Status: types.EventConfirmed}}
+				block.record = &types.Block{Number: number}
+			}
+
+			if err := c.handleReorg(ctx, client); err != nil {
+				t.Fatalf("handleReorg: %v", err)
+			}
+
+			if c.lastBlockNumber != tt.wantNumber || c.lastBlockHash != tt.wantHash {
+				t.Errorf("rewound to %d %s, want %d %s", c.lastBlockNumber, c.lastBlockHash, tt.wantNumber, tt.wantHash)
+			}
+			var want []string
+			for _, number := range tt.retracted {
+				want = append(want,
+					fmt.Sprintf("retract %d removed", number),
+					fmt.Sprintf("retract call %d removed", number),
+					fmt.Sprintf("retract block %d", number))
+			}
+			if fmt.Sprint(output.records) != fmt.Sprint(want) {
+				t.Errorf("outputs = %v, want %v", output.records, want)
+			}
+			assertNumbers(t, "window", windowNumbers(c.window), tt.wantWindow)
+
+			checkpoint, err := checkpoints.LoadCheckpoint(ctx, "test")
+			if err != nil || checkpoint == nil {
+				t.Fatalf("checkpoint not saved: %v", err)
+			}
+			if checkpoint.BlockNumber != tt.wantNumber {
+				t.Errorf("checkpoint at %d, want %d", checkpoint.BlockNumber, tt.wantNumber)
+			}
+		})
+	}
+}
+
+func TestDeepReorgNumber(t *testing.T) {
+	tests := []struct {
+		lastBlockNumber uint64
+		startBlock      uint64
+		tracked         []uint64
+		want            uint64
+	}{
+		{100, 0, []uint64{90, 95, 100}, 89},
+		{100, 0, []uint64{100}, 92},
+		{100, 0, nil, 92},
+		{100, 95, []uint64{100}, 94},
+		{5, 0, []uint64{5}, 0},
+		{5, 0, []uint64{0, 5}, 0},
+	}
+	for _, tt := range tests {
+		c := &chain{
+			lastBlockNumber:  tt.lastBlockNumber,
+			startBlockNumber: tt.startBlock,
+			window:           newReorgWindow(8),
+		}
+		for _, number := range tt.tracked {
+			c.window.add(number, testHash(number, 0), true)
+		}
+		if got := c.deepReorgNumber(); got != tt.want {
+			t.Errorf("deepReorgNumber(%d, %d, %v) = %d, want %d", tt.lastBlockNumber, tt.startBlock, tt.tracked, got, tt.want)
+		}
+	}
+}
+
+func TestReorgDropsDiscoveredAddresses(t *testing.T) {
+	server := newFakeRPC(t, headersRPC(t, 12))
+	defer server.Close()
+	ctx := context.Background()
+	client, err := dialChainClient(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	checkpoints := NewFileCheckpoints(filepath.Join(t.TempDir(), "checkpoints.json"))
+	c := &chain{
+		name:            "test",
+		logger:          zap.NewNop().Sugar(),
+		checkpoints:     checkpoints,
+		addressMap:      make(map[ethcommon.Address]types.Contract),
+		lastBlockNumber: 14,
+		lastBlockHash:   testHash(14, 0),
+		window:          newReorgWindow(4),
+	}
+	child := types.NewContract("test", "pools", &ethabi.ABI{}, nil, types.ContractOptions{})
+	discover := func(number uint64, address ethcommon.Address) *discoveredAddress {
+		c.addAddress(child, address)
+		discovered := &discoveredAddress{contract: child, address: address, blockNumber: number, blockHash: testHash(number, 0)}
+		c.discovered = append(c.discovered, discovered)
+		return discovered
+	}
+
+	kept := ethcommon.HexToAddress("0x0a")
+	confirmed := ethcommon.HexToAddress("0x0b")
+	unconfirmed := ethcommon.HexToAddress("0x0c")
+	pending := ethcommon.HexToAddress("0x0d")
+	headers := make(map[uint64]*rpcBlock)
+	for number := uint64(10); number <= 14; number++ {
+		c.window.add(number, testHash(number, 0), number <= 13)
+		headers[number] = &rpcBlock{Hash: testHash(number, 0)}
+	}
+	discover(11, kept)
+	discover(13, confirmed)
+	discover(14, unconfirmed)
+	c.trackDiscoveredAddresses(ctx, headers)
+	discover(15, pending)
+
+	saved, _ := checkpoints.LoadAddresses(ctx, "test", "pools")
+	if fmt.Sprint(saved) != fmt.Sprint([]ethcommon.Address{kept, confirmed}) {
+		t.Fatalf("saved addresses = %v, want the ones of confirmed blocks", saved)
+	}
+
+	if err := c.handleReorg(ctx, client); err != nil {
+		t.Fatalf("handleReorg: %v", err)
+	}
+
+	saved, _ = checkpoints.LoadAddresses(ctx, "test", "pools")
+	if fmt.Sprint(saved) != fmt.Sprint([]ethcommon.Address{kept}) {
+		t.Errorf("saved addresses after reorg = %v, want %v", saved, kept)
+	}
+	if fmt.Sprint(child.Addresses()) != fmt.Sprint([]ethcommon.Address{kept}) || fmt.Sprint(c.addresses) != fmt.Sprint([]ethcommon.Address{kept}) {
+		t.Errorf("watched addresses after reorg = %v %v, want %v", child.Addresses(), c.addresses, kept)
+	}
+	for _, address := range []ethcommon.Address{confirmed, unconfirmed, pending} {
+		if _, exists := c.addressMap[address]; exists {
+			t.Errorf("address %s of an orphaned block is still watched", address)
+		}
+	}
+	if len(c.discovered) != 0 {
+		t.Errorf("pending discovered addresses are not dropped")
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newFakeRPC serves results of the handler, single and batch requests are supported.
+// Requests the handler has no result for fail as unknown methods.
+func newFakeRPC(t *testing.T, handle func(req rpcRequest) (string, bool)) *httptest.Server {
+	respond := func(req rpcRequest) rpcResponse {
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+		if result, ok := handle(req); ok {
+			resp.Result = json.RawMessage(result)
+		} else {
+			resp.Error = &rpcError{Code: -32601, Message: "method not found"}
+		}
+		return resp
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if len(body) > 0 && body[0] == '[' {
+			var reqs []rpcRequest
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				t.Errorf("invalid batch: %v", err)
+				return
+			}
+			resps := make([]rpcResponse, len(reqs))
+			for i, req := range reqs {
+				resps[i] = respond(req)
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(respond(req))
+	}))
+}
+
+// cannedResults returns the same result for all requests of a method.
+func cannedResults(results map[string]string) func(req rpcRequest) (string, bool) {
+	return func(req rpcRequest) (string, bool) {
+		result, ok := results[req.Method]
+		return result, ok
+	}
+}
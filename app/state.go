@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+// stateCall is a state query of a contract address.
+type stateCall struct {
+	contract types.Contract
+	query    *types.StateQuery
+	address  ethcommon.Address
+}
+
+// hasStateContracts tells whether any contract of the chain has state queries.
+func hasStateContracts(contracts []types.Contract) bool {
+	for _, contract := range contracts {
+		if len(contract.StateQueries()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pollState calls the view functions that are due at the confirmed block, in a single batch.
+func (c *chain) pollState(ctx context.Context, client *chainClient) error {
+	blockNumber := c.confirmedNumber
+	var calls []stateCall
+	var dueKeys []string
+	for _, contract := range c.contracts {
+		queries := contract.StateQueries()
+		for i := range queries {
+			key := stateQueryKey(contract, &queries[i])
+			if polledAt, exists := c.statePolledAt[key]; exists && blockNumber < polledAt+queries[i].Every {
+				continue
+			}
+			dueKeys = append(dueKeys, key)
+			for _, address := range contract.Addresses() {
+				calls = append(calls, stateCall{contract: contract, query: &queries[i], address: address})
+			}
+		}
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+
+	headers, err := client.HeadersByNumber(ctx, []uint64{blockNumber})
+	if err != nil {
+		return fmt.Errorf("call to HeadersByNumber failed: %w", err)
+	}
+	header := headers[blockNumber]
+	msgs := make([]ethereum.CallMsg, len(calls))
+	for i := range calls {
+		msgs[i] = ethereum.CallMsg{To: &calls[i].address, Data: calls[i].query.Data}
+	}
+	results, errs, err := client.CallContracts(ctx, msgs, blockNumber)
+	if err != nil {
+		return fmt.Errorf("call to CallContracts failed: %w", err)
+	}
+	for _, key := range dueKeys {
+		c.statePolledAt[key] = blockNumber
+	}
+
+	blockTs := time.Unix(int64(header.Timestamp), 0)
+	for i, call := range calls {
+		contract := call.contract
+		if errs[i] != nil {
+			common.PromStateErrors.WithLabelValues(c.name, contract.Name(), call.query.Method).Inc()
+			c.logger.Warnw("State call failed", "contractName", contract.Name(), "address", call.address, "method", call.query.Method, "err", errs[i])
+			continue
+		}
+		values, err := decodeStateValues(contract, call.query.Method, results[i])
+		if err != nil {
+			common.PromStateErrors.WithLabelValues(c.name, contract.Name(), call.query.Method).Inc()
+			c.logger.Warnw("Could not decode state", "contractName", contract.Name(), "address", call.address, "method", call.query.Method, "err", err)
+			continue
+		}
+
+		state := &types.State{
+			MethodName:  call.query.Method,
+			MethodArgs:  call.query.Args,
+			Values:      values,
+			Contract:    contract,
+			ChainID:     c.chainID,
+			Address:     call.address,
+			BlockTs:     blockTs,
+			BlockNumber: blockNumber,
+			BlockHash:   header.Hash,
+		}
+		c.reportStateGauges(state)
+		encodeNumbers(state.Values, contract.NumberEncoding())
+		c.outputs.WriteState(state)
+	}
+	return nil
+}
+
+func stateQueryKey(contract types.Contract, query *types.StateQuery) string {
+	return contract.Name() + "." + query.Method + "(" + strings.Join(query.Args, ",") + ")"
+}
+
+// reportStateGauges exposes numeric and boolean state values as Prometheus gauges.
+func (c *chain) reportStateGauges(state *types.State) {
+	args := strings.Join(state.MethodArgs, ",")
+	for name, value := range state.Values {
+		var number float64
+		switch v := value.(type) {
+		case *big.Int:
+			number, _ = new(big.Float).SetInt(v).Float64()
+		case bool:
+			if v {
+				number = 1
+			}
+		default:
+			rv := reflect.ValueOf(value)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				number = float64(rv.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				number = float64(rv.Uint())
+			default:
+				continue
+			}
+		}
+		common.PromState.WithLabelValues(c.name, state.Contract.Name(), state.Address.Hex(), state.MethodName, args, name).Set(number)
+	}
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// debugFrame is a frame of the geth callTracer output.
+type debugFrame struct {
+	Type  string             `json:"type"`
+	From  ethcommon.Address  `json:"from"`
+	To    *ethcommon.Address `json:"to"`
+	Value *hexutil.Big       `json:"value"`
+	Input hexutil.Bytes      `json:"input"`
+	Error string             `json:"error"`
+	Calls []debugFrame       `json:"calls"`
+}
+
+type debugTraceResult struct {
+	Result *debugFrame `json:"result"`
+	Error  string      `json:"error"`
+}
+
+// parityTrace is an element of the trace_block output.
+type parityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType string             `json:"callType"`
+		From     ethcommon.Address  `json:"from"`
+		To       *ethcommon.Address `json:"to"`
+		Value    *hexutil.Big       `json:"value"`
+		Input    hexutil.Bytes      `json:"input"`
+		// Init and CreationMethod are set for contract creations instead of Input and CallType.
+		Init           hexutil.Bytes `json:"init"`
+		CreationMethod string        `json:"creationMethod"`
+	} `json:"action"`
+	Result *struct {
+		// Address is the created contract.
+		Address *ethcommon.Address `json:"address"`
+	} `json:"result"`
+	Error               string          `json:"error"`
+	TraceAddress        []uint          `json:"traceAddress"`
+	TransactionHash     *ethcommon.Hash `json:"transactionHash"`
+	TransactionPosition *uint           `json:"transactionPosition"`
+	BlockHash           ethcommon.Hash  `json:"blockHash"`
+}
+
+// TraceBlocks returns call frames of the blocks using debug_traceBlockByNumber with
+// the callTracer, or trace_block of the parity trace module.
+func (c *chainClient) TraceBlocks(ctx context.Context, tracer string, blocks []*rpcBlock) ([][]callFrame, error) {
+	batch := make([]rpc.BatchElem, len(blocks))
+	debugResults := make([][]debugTraceResult, len(blocks))
+	parityResults := make([][]parityTrace, len(blocks))
+	for i, block := range blocks {
+		blockNumber := hexutil.EncodeUint64(uint64(block.Number))
+		if tracer == TracerParity {
+			batch[i] = rpc.BatchElem{
+				Method: "trace_block",
+				Args:   []interface{}{blockNumber},
+				Result: &parityResults[i],
+			}
+		} else {
+			batch[i] = rpc.BatchElem{
+				Method: "debug_traceBlockByNumber",
+				Args:   []interface{}{blockNumber, map[string]interface{}{"tracer": "callTracer"}},
+				Result: &debugResults[i],
+			}
+		}
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	frames := make([][]callFrame, len(blocks))
+	for i, block := range blocks {
+		var err error
+		if tracer == TracerParity {
+			frames[i], err = parityFrames(block, parityResults[i])
+		} else {
+			frames[i], err = debugFrames(block, debugResults[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %v", uint64(block.Number), err)
+		}
+	}
+	return frames, nil
+}
+
+// debugFrames flattens callTracer results, which are in the order of the block transactions.
+func debugFrames(block *rpcBlock, results []debugTraceResult) ([]callFrame, error) {
+	if len(results) != len(block.Transactions) {
+		return nil, fmt.Errorf("got %d traces for %d transactions", len(results), len(block.Transactions))
+	}
+	var frames []callFrame
+	for i, result := range results {
+		if result.Error != "" || result.Result == nil {
+			return nil, fmt.Errorf("transaction %s trace failed: %s", block.Transactions[i].Hash, result.Error)
+		}
+		frames = appendDebugFrames(frames, block.Transactions[i].Hash, uint(i), result.Result, "", true)
+	}
+	return frames, nil
+}
+
+func appendDebugFrames(frames []callFrame, txHash ethcommon.Hash, txIndex uint, frame *debugFrame, traceAddress string, parentSuccess bool) []callFrame {
+	// Calls of a reverted frame are reverted as well.
+	success := parentSuccess && frame.Error == ""
+	if isTracedCallType(frame.Type) && frame.To != nil {
+		frames = append(frames, callFrame{
+			txHash:       txHash,
+			txIndex:      txIndex,
+			callType:     strings.ToUpper(frame.Type),
+			traceAddress: traceAddress,
+			from:         frame.From,
+			to:           *frame.To,
+			value:        (*big.Int)(frame.Value),
+			input:        frame.Input,
+			success:      success,
+		})
+	}
+	for i := range frame.Calls {
+		childAddress := strconv.Itoa(i)
+		if traceAddress != "" {
+			childAddress = traceAddress + "." + childAddress
+		}
+		frames = appendDebugFrames(frames, txHash, txIndex, &frame.Calls[i], childAddress, success)
+	}
+	return frames
+}
+
+// parityFrames converts trace_block results, where a frame always follows its parent.
+func parityFrames(block *rpcBlock, traces []parityTrace) ([]callFrame, error) {
+	var frames []callFrame
+	failed := make(map[string]bool)
+	for _, trace := range traces {
+		if trace.TransactionHash == nil || trace.TransactionPosition == nil {
+			// Block and uncle rewards are not related to transactions.
+			continue
+		}
+		if trace.BlockHash != block.Hash {
+			return nil, fmt.Errorf("trace is in block %s instead of %s", trace.BlockHash, block.Hash)
+		}
+
+		path := make([]string, len(trace.TraceAddress))
+		for i, index := range trace.TraceAddress {
+			path[i] = strconv.FormatUint(uint64(index), 10)
+		}
+		traceAddress := strings.Join(path, ".")
+		key := trace.TransactionHash.Hex() + "/" + traceAddress
+		failed[key] = trace.Error != ""
+		if len(path) > 0 {
+			// Calls of a reverted frame are reverted as well.
+			parentKey := trace.TransactionHash.Hex() + "/" + strings.Join(path[:len(path)-1], ".")
+			failed[key] = failed[key] || failed[parentKey]
+		}
+
+		callType, to, input := trace.Action.CallType, trace.Action.To, trace.Action.Input
+		if trace.Type == "create" {
+			callType, to, input = "create", nil, trace.Action.Init
+			if trace.Action.CreationMethod != "" {
+				callType = trace.Action.CreationMethod
+			}
+			if trace.Result != nil {
+				to = trace.Result.Address
+			}
+		} else if trace.Type != "call" {
+			continue
+		}
+		if to == nil || !isTracedCallType(callType) {
+			continue
+		}
+		frames = append(frames, callFrame{
+			txHash:       *trace.TransactionHash,
+			txIndex:      *trace.TransactionPosition,
+			callType:     strings.ToUpper(callType),
+			traceAddress: traceAddress,
+			from:         trace.Action.From,
+			to:           *to,
+			value:        (*big.Int)(trace.Action.Value),
+			input:        input,
+			success:      !failed[key],
+		})
+	}
+	return frames, nil
+}
+
+// isTracedCallType tells whether a frame is a message call or a contract creation, self-destructs are skipped.
+// Frames of all call types are returned, contracts choose the call types they decode.
+func isTracedCallType(callType string) bool {
+	switch strings.ToUpper(callType) {
+	case "CALL", "DELEGATECALL", "STATICCALL", "CALLCODE", "CREATE", "CREATE2":
+		return true
+	}
+	return false
+}
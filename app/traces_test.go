@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+var (
+	traceBlockHash = ethcommon.HexToHash("0xb10c")
+	traceTx1       = ethcommon.HexToHash("0x01")
+	traceTx2       = ethcommon.HexToHash("0x02")
+)
+
+// debugTraceResponse is a canned debug_traceBlockByNumber output with the callTracer:
+// the first transaction calls a contract, whose reverted call has a nested call,
+// the second one deploys a contract which calls another contract.
+const debugTraceResponse = `[
+  {"result": {
+    "type": "CALL", "from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000bb",
+    "value": "0x1", "input": "0x01",
+    "calls": [
+      {"type": "STATICCALL", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000cc", "input": "0x02"},
+      {"type": "DELEGATECALL", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000dd", "input": "0x03", "error": "execution reverted",
+       "calls": [
+         {"type": "CALL", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000ee", "value": "0x0", "input": "0x04"}
+       ]}
+    ]}},
+  {"result": {
+    "type": "CREATE", "from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000ff", "value": "0x0", "input": "0x60",
+    "calls": [
+      {"type": "CALL", "from": "0x00000000000000000000000000000000000000ff", "to": "0x00000000000000000000000000000000000000cc", "value": "0x0", "input": "0x05"}
+    ]}}
+]`
+
+// parityTraceResponse is the same block as the trace_block output, with a block reward.
+const parityTraceResponse = `[
+  {"type": "call", "action": {"callType": "call", "from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000bb", "value": "0x1", "input": "0x01"},
+   "traceAddress": [], "transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001", "transactionPosition": 0,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"},
+  {"type": "call", "action": {"callType": "staticcall", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000cc", "value": "0x0", "input": "0x02"},
+   "traceAddress": [0], "transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001", "transactionPosition": 0,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"},
+  {"type": "call", "action": {"callType": "delegatecall", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000dd", "value": "0x0", "input": "0x03"},
+   "error": "Reverted", "traceAddress": [1], "transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001", "transactionPosition": 0,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"},
+  {"type": "call", "action": {"callType": "call", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000ee", "value": "0x0", "input": "0x04"},
+   "traceAddress": [1, 0], "transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001", "transactionPosition": 0,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"},
+  {"type": "create", "action": {"from": "0x00000000000000000000000000000000000000aa", "value": "0x0", "init": "0x60"},
+   "result": {"address": "0x00000000000000000000000000000000000000ff", "code": "0x60", "gasUsed": "0x1"},
+   "traceAddress": [], "transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000002", "transactionPosition": 1,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"},
+  {"type": "call", "action": {"callType": "call", "from": "0x00000000000000000000000000000000000000ff", "to": "0x00000000000000000000000000000000000000cc", "value": "0x0", "input": "0x05"},
+   "traceAddress": [0], "transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000002", "transactionPosition": 1,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"},
+  {"type": "reward", "action": {"author": "0x00000000000000000000000000000000000000aa", "rewardType": "block", "value": "0x1bc16d674ec80000"},
+   "traceAddress": [], "transactionHash": null, "transactionPosition": null,
+   "blockHash": "0x000000000000000000000000000000000000000000000000000000000000b10c"}
+]`
+
+func traceBlock() *rpcBlock {
+	return &rpcBlock{
+		Hash:   traceBlockHash,
+		Number: 100,
+		Transactions: []rpcTransaction{
+			{Hash: traceTx1, TransactionIndex: 0},
+			{Hash: traceTx2, TransactionIndex: 1},
+		},
+	}
+}
+
+type expectedFrame struct {
+	txHash       ethcommon.Hash
+	txIndex      uint
+	callType     string
+	traceAddress string
+	to           ethcommon.Address
+	input        string
+	success      bool
+}
+
+// Both tracers must yield the same frames for the canned block, block rewards are skipped.
+var expectedFrames = []expectedFrame{
+	{traceTx1, 0, "CALL", "", ethcommon.HexToAddress("0xbb"), "01", true},
+	{traceTx1, 0, "STATICCALL", "0", ethcommon.HexToAddress("0xcc"), "02", true},
+	{traceTx1, 0, "DELEGATECALL", "1", ethcommon.HexToAddress("0xdd"), "03", false},
+	{traceTx1, 0, "CALL", "1.0", ethcommon.HexToAddress("0xee"), "04", false},
+	{traceTx2, 1, "CREATE", "", ethcommon.HexToAddress("0xff"), "60", true},
+	{traceTx2, 1, "CALL", "0", ethcommon.HexToAddress("0xcc"), "05", true},
+}
+
+func TestTraceBlocks(t *testing.T) {
+	tests := []struct {
+		tracer  string
+		method  string
+		payload string
+	}{
+		{TracerDebug, "debug_traceBlockByNumber", debugTraceResponse},
+		{TracerParity, "trace_block", parityTraceResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tracer, func(t *testing.T) {
+			server := newFakeRPC(t, cannedResults(map[string]string{tt.method: tt.payload}))
+			defer server.Close()
+
+			ctx := context.Background()
+			client, err := dialChainClient(ctx, server.URL)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer client.Close()
+
+			frames, err := client.TraceBlocks(ctx, tt.tracer, []*rpcBlock{traceBlock()})
+			if err != nil {
+				t.Fatalf("TraceBlocks: %v", err)
+			}
+			if len(frames) != 1 {
+				t.Fatalf("got frames of %d blocks, want 1", len(frames))
+			}
+			assertFrames(t, frames[0])
+		})
+	}
+}
+
+func TestTraceBlocksErrors(t *testing.T) {
+	ctx := context.Background()
+
+	// callTracer results must match the block transactions.
+	server := newFakeRPC(t, cannedResults(map[string]string{"debug_traceBlockByNumber": `[{"result": {"type": "CALL", "from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000bb"}}]`}))
+	defer server.Close()
+	client, err := dialChainClient(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.TraceBlocks(ctx, TracerDebug, []*rpcBlock{traceBlock()}); err == nil {
+		t.Errorf("expected an error for missing transaction traces")
+	}
+
+	// trace_block of another block, e.g. after a reorg.
+	block := traceBlock()
+	block.Hash = ethcommon.HexToHash("0xb10d")
+	server = newFakeRPC(t, cannedResults(map[string]string{"trace_block": parityTraceResponse}))
+	defer server.Close()
+	client, err = dialChainClient(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.TraceBlocks(ctx, TracerParity, []*rpcBlock{block}); err == nil {
+		t.Errorf("expected an error for traces of another block")
+	}
+}
+
+func TestCallTypes(t *testing.T) {
+	tests := []struct {
+		callTypes []string
+		want      []string
+	}{
+		{[]string{CallTypeCall, CallTypeCreate}, []string{"CALL", "CALL", "CREATE", "CALL"}},
+		{[]string{CallTypeCall, CallTypeStaticCall, CallTypeDelegateCall}, []string{"CALL", "STATICCALL", "DELEGATECALL", "CALL", "CALL"}},
+		{[]string{CallTypeCreate}, []string{"CREATE"}},
+	}
+	for _, tt := range tests {
+		callTypes := make(map[string]struct{})
+		for _, callType := range tt.callTypes {
+			callTypes[callType] = struct{}{}
+		}
+		contract := types.NewContract("test", "target", &ethabi.ABI{}, nil, types.ContractOptions{Calls: true, CallTypes: callTypes})
+		c := &chain{addressMap: make(map[ethcommon.Address]types.Contract)}
+		for _, frame := range expectedFrames {
+			c.addressMap[frame.to] = contract
+		}
+
+		var got []string
+		for _, want := range expectedFrames {
+			frame := &callFrame{callType: want.callType, to: want.to}
+			if c.callContract(frame) != nil {
+				got = append(got, frame.callType)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("call types %v: got frames %v, want %v", tt.callTypes, got, tt.want)
+		}
+	}
+}
+
+func assertFrames(t *testing.T, frames []callFrame) {
+	t.Helper()
+	if len(frames) != len(expectedFrames) {
+		t.Fatalf("got %d frames, want %d: %+v", len(frames), len(expectedFrames), frames)
+	}
+	for i, want := range expectedFrames {
+		got := frames[i]
+		if got.txHash != want.txHash || got.txIndex != want.txIndex {
+			t.Errorf("frame %d: got tx %s/%d, want %s/%d", i, got.txHash, got.txIndex, want.txHash, want.txIndex)
+		}
+		if got.callType != want.callType {
+			t.Errorf("frame %d: got call type %s, want %s", i, got.callType, want.callType)
+		}
+		if got.traceAddress != want.traceAddress {
+			t.Errorf("frame %d: got trace address %q, want %q", i, got.traceAddress, want.traceAddress)
+		}
+		if got.to != want.to {
+			t.Errorf("frame %d: got to %s, want %s", i, got.to, want.to)
+		}
+		if ethcommon.Bytes2Hex(got.input) != want.input {
+			t.Errorf("frame %d: got input %x, want %s", i, got.input, want.input)
+		}
+		if got.success != want.success {
+			t.Errorf("frame %d: got success %v, want %v", i, got.success, want.success)
+		}
+	}
+}
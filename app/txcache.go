@@ -0,0 +1,56 @@
+package app
+
+import (
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pinebit/lognite/app/types"
+)
+
+type cachedBlock struct {
+	number uint64
+	txs    map[ethcommon.Hash]*types.Transaction
+}
+
+// txCache keeps enriched transactions by the hash of their block, so that retried ranges
+// and blocks re-ingested after a reorg are not fetched again. Transactions of reorged
+// blocks are never hit as their block hashes differ. Blocks are kept as long as
+// they can be reorged, i.e. within the reorg window.
+type txCache struct {
+	size   uint64
+	blocks map[ethcommon.Hash]*cachedBlock
+}
+
+func newTxCache(size uint64) *txCache {
+	return &txCache{
+		size:   size,
+		blocks: make(map[ethcommon.Hash]*cachedBlock),
+	}
+}
+
+// get returns the cached transaction of the block, nil if there is none.
+func (c *txCache) get(blockHash, txHash ethcommon.Hash) *types.Transaction {
+	if block, exists := c.blocks[blockHash]; exists {
+		return block.txs[txHash]
+	}
+	return nil
+}
+
+func (c *txCache) add(blockNumber uint64, blockHash, txHash ethcommon.Hash, tx *types.Transaction) {
+	block, exists := c.blocks[blockHash]
+	if !exists {
+		block = &cachedBlock{
+			number: blockNumber,
+			txs:    make(map[ethcommon.Hash]*types.Transaction),
+		}
+		c.blocks[blockHash] = block
+	}
+	block.txs[txHash] = tx
+}
+
+// prune drops blocks that are too deep to be reorged.
+func (c *txCache) prune(lastBlockNumber uint64) {
+	for hash, block := range c.blocks {
+		if block.number+c.size <= lastBlockNumber {
+			delete(c.blocks, hash)
+		}
+	}
+}
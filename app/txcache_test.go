@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pinebit/lognite/app/types"
+)
+
+func TestGetTransactionsCache(t *testing.T) {
+	requests := 0
+	receiptBlock := testHash(10, 0)
+	server := newFakeRPC(t, func(req rpcRequest) (string, bool) {
+		requests++
+		switch req.Method {
+		case "eth_getTransactionByHash":
+			return `{"from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000bb", "value": "0x1", "input": "0xa9059cbb"}`, true
+		case "eth_getTransactionReceipt":
+			return fmt.Sprintf(`{"blockHash": "%s", "status": "0x1", "gasUsed": "0x5208", "effectiveGasPrice": "0x1"}`, receiptBlock.Hex()), true
+		}
+		return "", false
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := dialChainClient(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	c := &chain{txCache: newTxCache(4)}
+	contract := types.NewContract("test", "token", &ethabi.ABI{}, nil, types.ContractOptions{Enrich: true})
+	txHash := ethcommon.HexToHash("0x01")
+	logs := func(blockHash ethcommon.Hash) []contractLog {
+		return []contractLog{{Log: ethtypes.Log{BlockNumber: 10, BlockHash: blockHash, TxHash: txHash}, contract: contract}}
+	}
+
+	for i := 0; i < 2; i++ {
+		txs, err := c.getTransactions(ctx, client, logs(testHash(10, 0)))
+		if err != nil {
+			t.Fatalf("getTransactions: %v", err)
+		}
+		if tx := txs[txHash]; tx == nil || tx.GasUsed != 21000 || tx.Selector != "0xa9059cbb" {
+			t.Fatalf("unexpected transaction: %+v", tx)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2 for the transaction and receipt fetched once", requests)
+	}
+
+	// The same transaction included in a block of another fork is fetched again.
+	receiptBlock = testHash(10, 1)
+	if _, err := c.getTransactions(ctx, client, logs(testHash(10, 1))); err != nil {
+		t.Fatalf("getTransactions: %v", err)
+	}
+	if requests != 4 {
+		t.Errorf("got %d requests, want 4 after the fork", requests)
+	}
+
+	c.txCache.prune(13)
+	if len(c.txCache.blocks) != 2 {
+		t.Errorf("got %d cached blocks within the window, want 2", len(c.txCache.blocks))
+	}
+	c.txCache.prune(14)
+	if len(c.txCache.blocks) != 0 {
+		t.Errorf("got %d cached blocks below the window, want 0", len(c.txCache.blocks))
+	}
+}
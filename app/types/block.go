@@ -0,0 +1,24 @@
+package types
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Block is a record of a confirmed block.
+type Block struct {
+	ChainName  string
+	ChainID    uint64
+	Number     uint64
+	Hash       common.Hash
+	ParentHash common.Hash
+	BlockTs    time.Time
+	GasUsed    uint64
+	GasLimit   uint64
+	// BaseFee is nil for blocks before London.
+	BaseFee *big.Int
+	TxCount int
+	Miner   common.Address
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Call is a decoded call to a watched contract address: a transaction, or an internal call when traced.
+type Call struct {
+	MethodName string
+	MethodArgs map[string]interface{}
+	Contract   Contract
+
+	ChainID uint64
+	Address common.Address
+	From    common.Address
+	Value   *big.Int
+	// CallType is CALL, CREATE, CREATE2, DELEGATECALL, STATICCALL or CALLCODE.
+	// Creations are decoded as the constructor, without arguments.
+	CallType string
+	// TraceAddress is the path of an internal call in the call tree, e.g. "0.1", empty for transactions.
+	TraceAddress string
+	BlockTs      time.Time
+	BlockNumber  uint64
+	BlockHash    common.Hash
+	TxHash       common.Hash
+	TxIndex      uint
+	// Success is false for reverted calls.
+	Success bool
+	// Status is pending for calls of transactions seen in the mempool, they have no block,
+	// and removed for calls of blocks orphaned by a reorg.
+	Status EventStatus
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type Checkpoint struct {
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+}
+
+// Checkpoints persist the ingestion progress of chains: the last processed block
+// and the contract addresses discovered from factory events up to it.
+type Checkpoints interface {
+	LoadCheckpoint(ctx context.Context, chainName string) (*Checkpoint, error)
+	SaveCheckpoint(ctx context.Context, chainName string, checkpoint Checkpoint) error
+	LoadAddresses(ctx context.Context, chainName, contractName string) ([]common.Address, error)
+	SaveAddress(ctx context.Context, chainName, contractName string, address common.Address) error
+	// RemoveAddress forgets an address discovered in a block that got orphaned by a reorg.
+	RemoveAddress(ctx context.Context, chainName, contractName string, address common.Address) error
+}
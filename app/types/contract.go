@@ -10,26 +10,88 @@ type Contract interface {
 	Name() string
 	ABI() *abi.ABI
 	Addresses() []common.Address
+	AddAddress(address common.Address)
+	RemoveAddress(address common.Address)
 	IsEventAllowed(name string) bool
+	StartBlock() uint64
+	Factory() *Factory
+	IsWildcard() bool
+	TopicFilters() map[string][][]common.Hash
+	Predicate(eventName string) Predicate
+	IsEnriched() bool
+	HasCalls() bool
+	HasCallType(callType string) bool
+	StateQueries() []StateQuery
+	NumberEncoding() string
+	HasRawLogs() bool
+	AnonymousEvents() []string
+}
+
+// StateQuery is a view function call, packed with its arguments, polled every N blocks.
+type StateQuery struct {
+	Method string
+	// Args are the configured argument values, used to tell apart queries of the same method.
+	Args  []string
+	Data  []byte
+	Every uint64
+}
+
+// Predicate decides whether a decoded event is delivered to outputs.
+type Predicate interface {
+	Match(args map[string]interface{}) (bool, error)
 }
 
 type ContractsPerChain map[string][]Contract
 
+// Factory describes where addresses of a contract are discovered at runtime:
+// an address argument of an event emitted by another contract of the same chain.
+type Factory struct {
+	ContractName string
+	EventName    string
+	ArgName      string
+}
+
+type ContractOptions struct {
+	AllowedEvents map[string]struct{}
+	StartBlock    uint64
+	Factory       *Factory
+	// TopicFilters are indexed argument topics per event name, without the signature topic.
+	TopicFilters map[string][][]common.Hash
+	// Wildcard contracts have no addresses, their events are matched from any address.
+	Wildcard bool
+	// Predicates filter decoded events per event name.
+	Predicates map[string]Predicate
+	// Enrich attaches transaction and receipt details to events.
+	Enrich bool
+	// Calls enables decoding of transactions sent to the contract addresses.
+	Calls bool
+	// CallTypes are the decoded call types of traced frames, e.g. CALL and CREATE.
+	CallTypes map[string]struct{}
+	// StateQueries are view functions polled at the confirmed block.
+	StateQueries []StateQuery
+	// NumberEncoding is how decoded integers are output: raw, decimal, hex or number.
+	NumberEncoding string
+	// RawLogs enables output of logs that cannot be decoded, e.g. of unknown events.
+	RawLogs bool
+	// AnonymousEvents are names of anonymous events to match logs without a known signature with, in order.
+	AnonymousEvents []string
+}
+
 type contract struct {
-	chainName     string
-	name          string
-	abi           *abi.ABI
-	addresses     []common.Address
-	allowedEvents map[string]struct{}
+	chainName string
+	name      string
+	abi       *abi.ABI
+	addresses []common.Address
+	options   ContractOptions
 }
 
-func NewContract(chainName, contractName string, abi *abi.ABI, addresses []common.Address, allowedEvents map[string]struct{}) Contract {
+func NewContract(chainName, contractName string, abi *abi.ABI, addresses []common.Address, options ContractOptions) Contract {
 	return &contract{
-		chainName:     chainName,
-		name:          contractName,
-		abi:           abi,
-		addresses:     addresses,
-		allowedEvents: allowedEvents,
+		chainName: chainName,
+		name:      contractName,
+		abi:       abi,
+		addresses: addresses,
+		options:   options,
 	}
 }
 
@@ -37,14 +99,85 @@ func (c contract) Addresses() []common.Address {
 	return c.addresses
 }
 
+func (c *contract) AddAddress(address common.Address) {
+	c.addresses = append(c.addresses, address)
+}
+
+func (c *contract) RemoveAddress(address common.Address) {
+	c.addresses = removeAddress(c.addresses, address)
+}
+
+func removeAddress(addresses []common.Address, address common.Address) []common.Address {
+	var kept []common.Address
+	for _, a := range addresses {
+		if a != address {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
 func (c contract) IsEventAllowed(name string) bool {
-	if len(c.allowedEvents) == 0 {
+	if len(c.options.AllowedEvents) == 0 {
 		return true
 	}
-	_, exists := c.allowedEvents[name]
+	_, exists := c.options.AllowedEvents[name]
 	return exists
 }
 
+func (c contract) StartBlock() uint64 {
+	return c.options.StartBlock
+}
+
+func (c contract) Factory() *Factory {
+	return c.options.Factory
+}
+
+func (c contract) IsWildcard() bool {
+	return c.options.Wildcard
+}
+
+func (c contract) TopicFilters() map[string][][]common.Hash {
+	return c.options.TopicFilters
+}
+
+func (c contract) Predicate(eventName string) Predicate {
+	return c.options.Predicates[eventName]
+}
+
+func (c contract) IsEnriched() bool {
+	return c.options.Enrich
+}
+
+func (c contract) HasCalls() bool {
+	return c.options.Calls
+}
+
+// HasCallType tells whether calls of the type are decoded, CREATE2 frames count as CREATE.
+func (c contract) HasCallType(callType string) bool {
+	if callType == "CREATE2" {
+		callType = "CREATE"
+	}
+	_, exists := c.options.CallTypes[callType]
+	return exists
+}
+
+func (c contract) StateQueries() []StateQuery {
+	return c.options.StateQueries
+}
+
+func (c contract) NumberEncoding() string {
+	return c.options.NumberEncoding
+}
+
+func (c contract) HasRawLogs() bool {
+	return c.options.RawLogs
+}
+
+func (c contract) AnonymousEvents() []string {
+	return c.options.AnonymousEvents
+}
+
 func (c contract) ABI() *abi.ABI {
 	return c.abi
 }
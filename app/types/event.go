@@ -1,16 +1,26 @@
 package types
 
 import (
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
+type EventStatus string
+
+const (
+	EventConfirmed EventStatus = "confirmed"
+	EventPending   EventStatus = "pending"
+	EventRemoved   EventStatus = "removed"
+)
+
 type Event struct {
 	EventName string
 	EventArgs map[string]interface{}
 	Contract  Contract
 
+	ChainID     uint64
 	Address     common.Address
 	BlockTs     time.Time
 	BlockNumber uint64
@@ -18,4 +28,23 @@ type Event struct {
 	TxHash      common.Hash
 	TxIndex     uint
 	LogIndex    uint
+	Status      EventStatus
+
+	// Tx is set for contracts with enrichment enabled.
+	Tx *Transaction
+
+	// Topics and Data are the raw log, stored to re-decode the event after an ABI change.
+	Topics []common.Hash
+	Data   []byte
+}
+
+// Transaction holds details of the transaction that emitted an event, taken from the transaction and its receipt.
+type Transaction struct {
+	From              common.Address
+	To                *common.Address
+	Value             *big.Int
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+	// Selector is the hex-encoded first 4 bytes of the input, empty for plain transfers.
+	Selector string
 }
@@ -2,6 +2,25 @@ package types
 
 type Output interface {
 	Write(event *Event)
+	// Confirm signals that a previously written pending event got enough confirmations.
+	// The event is the same as written, except its status is confirmed.
+	Confirm(event *Event)
+	// Retract signals that a previously written event is no longer canonical due to a reorg.
+	// The event is the same as written, except its status is removed.
+	Retract(event *Event)
+	// WriteCall delivers a decoded call, calls are only written for confirmed blocks.
+	WriteCall(call *Call)
+	// RetractCall signals that a previously written call is no longer canonical due to a reorg.
+	// The call is the same as written, except its status is removed.
+	RetractCall(call *Call)
+	// WriteState delivers a result of a polled view function.
+	WriteState(state *State)
+	// WriteBlock delivers a record of a confirmed block.
+	WriteBlock(block *Block)
+	// RetractBlock signals that a previously written block is no longer canonical due to a reorg.
+	RetractBlock(block *Block)
+	// WriteRawLog delivers an undecodable log, raw logs are only written for confirmed blocks.
+	WriteRawLog(log *RawLog)
 }
 
 type Outputs []Output
@@ -11,3 +30,59 @@ func (o Outputs) Write(event *Event) {
 		output.Write(event)
 	}
 }
+
+func (o Outputs) WriteCall(call *Call) {
+	for _, output := range o {
+		output.WriteCall(call)
+	}
+}
+
+func (o Outputs) RetractCall(call *Call) {
+	removed := *call
+	removed.Status = EventRemoved
+	for _, output := range o {
+		output.RetractCall(&removed)
+	}
+}
+
+func (o Outputs) WriteState(state *State) {
+	for _, output := range o {
+		output.WriteState(state)
+	}
+}
+
+func (o Outputs) WriteBlock(block *Block) {
+	for _, output := range o {
+		output.WriteBlock(block)
+	}
+}
+
+func (o Outputs) RetractBlock(block *Block) {
+	for _, output := range o {
+		output.RetractBlock(block)
+	}
+}
+
+func (o Outputs) WriteRawLog(log *RawLog) {
+	for _, output := range o {
+		output.WriteRawLog(log)
+	}
+}
+
+// Confirm and Retract pass a copy of the event with the new status, so outputs
+// that still hold the original (e.g. in a queue) are not affected.
+func (o Outputs) Confirm(event *Event) {
+	confirmed := *event
+	confirmed.Status = EventConfirmed
+	for _, output := range o {
+		output.Confirm(&confirmed)
+	}
+}
+
+func (o Outputs) Retract(event *Event) {
+	removed := *event
+	removed.Status = EventRemoved
+	for _, output := range o {
+		output.Retract(&removed)
+	}
+}
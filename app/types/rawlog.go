@@ -0,0 +1,25 @@
+package types
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RawLog is a log of a watched contract that could not be decoded with its ABI.
+type RawLog struct {
+	Contract Contract
+
+	ChainID     uint64
+	Address     common.Address
+	Topics      []common.Hash
+	Data        []byte
+	BlockTs     time.Time
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	TxIndex     uint
+	LogIndex    uint
+	// Error tells why the log could not be decoded.
+	Error string
+}
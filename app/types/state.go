@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// State is a result of a view function call at a confirmed block.
+type State struct {
+	MethodName string
+	MethodArgs []string
+	// Values are the decoded outputs by name, unnamed outputs are named by their position, e.g. "output0".
+	Values   map[string]interface{}
+	Contract Contract
+
+	ChainID     uint64
+	Address     common.Address
+	BlockTs     time.Time
+	BlockNumber uint64
+	BlockHash   common.Hash
+}
@@ -13,9 +13,12 @@ const defaultConfigPath = "config/config.yaml"
 
 func main() {
 	flag.Parse()
+	if flag.NArg() > 0 && flag.Arg(0) == "redecode" {
+		redecode(flag.Args()[1:])
+		return
+	}
 	if flag.NArg() > 1 {
-		fmt.Println("Usage: lognite [path-to-config-yaml]")
-		os.Exit(1)
+		usage()
 	}
 
 	configPath := defaultConfigPath
@@ -31,3 +34,26 @@ func main() {
 		os.Exit(0)
 	}
 }
+
+// redecode re-decodes stored logs of a contract with its current ABI.
+func redecode(args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		usage()
+	}
+
+	configPath := defaultConfigPath
+	if len(args) == 3 {
+		configPath = args[2]
+	}
+
+	app := app.NewApp(configPath)
+	if err := app.Redecode(args[0], args[1]); err != nil {
+		log.Fatalf("Redecode error: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: lognite [path-to-config-yaml]")
+	fmt.Println("       lognite redecode <chain> <contract> [path-to-config-yaml]")
+	os.Exit(1)
+}